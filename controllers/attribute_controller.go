@@ -0,0 +1,460 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// код атрибута попадает в параметры запроса (attr.<code>) и в SQL сортировки,
+// поэтому допускаются только латиница в нижнем регистре, цифры и «_»
+var attrCodeRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// attrValueSQL — значение атрибута товара по коду (подзапрос для сортировки).
+const attrValueSQL = `(SELECT av.number_value FROM product_attribute_values av
+	JOIN attributes a ON a.id = av.attribute_id AND a.deleted_at IS NULL
+	WHERE av.product_id = products.id AND a.code = '%s' LIMIT 1)`
+
+// pricePerServingSQL — текущая цена, делённая на число порций.
+var pricePerServingSQL = "(" + effectivePriceSQL + " / NULLIF(" + fmt.Sprintf(attrValueSQL, models.AttrServings) + ", 0))"
+
+// errAttributeInput — значение атрибута не проходит проверку по его описанию.
+type errAttributeInput struct {
+	msg string
+}
+
+func (e *errAttributeInput) Error() string { return e.msg }
+
+func isAttributeInputErr(err error) (*errAttributeInput, bool) {
+	var ae *errAttributeInput
+	ok := errors.As(err, &ae)
+	return ae, ok
+}
+
+// applyAttributes проверяет значения по атрибутам категории и заменяет ими
+// значения товара. null у ключа — значение не задано. При lenient коды, которых
+// нет в категории, молча отбрасываются (перенос товара в другую категорию).
+func applyAttributes(tx *gorm.DB, productID, categoryID uint, values map[string]interface{}, lenient bool) ([]models.ProductAttributeValue, error) {
+	var defs []models.Attribute
+	if err := tx.Where("category_id = ?", categoryID).
+		Order("sort_order asc, id asc").
+		Find(&defs).Error; err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.Attribute, len(defs))
+	for _, d := range defs {
+		byCode[d.Code] = d
+	}
+
+	for code := range values {
+		if _, ok := byCode[code]; !ok && !lenient {
+			return nil, &errAttributeInput{msg: fmt.Sprintf("unknown attribute '%s'", code)}
+		}
+	}
+
+	result := make([]models.ProductAttributeValue, 0, len(values))
+	for _, d := range defs {
+		raw, ok := values[d.Code]
+		if !ok || raw == nil {
+			if d.Required {
+				return nil, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' is required", d.Code)}
+			}
+			continue
+		}
+		v, err := attributeValue(d, raw)
+		if err != nil {
+			return nil, err
+		}
+		v.ProductID = productID
+		result = append(result, v)
+	}
+
+	if err := tx.Where("product_id = ?", productID).Delete(&models.ProductAttributeValue{}).Error; err != nil {
+		return nil, err
+	}
+	if len(result) > 0 {
+		if err := tx.Omit("Attribute").Create(&result).Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// attributeValue приводит значение из JSON к типу атрибута.
+func attributeValue(d models.Attribute, raw interface{}) (models.ProductAttributeValue, error) {
+	v := models.ProductAttributeValue{AttributeID: d.ID, Attribute: d}
+
+	switch d.Type {
+	case models.AttrNumber:
+		n, ok := raw.(float64)
+		if !ok {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be a number", d.Code)}
+		}
+		if (d.Min != nil && n < *d.Min) || (d.Max != nil && n > *d.Max) {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' is out of range", d.Code)}
+		}
+		v.NumberValue = &n
+	case models.AttrBool:
+		b, ok := raw.(bool)
+		if !ok {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be true or false", d.Code)}
+		}
+		v.TextValue = strconv.FormatBool(b)
+	case models.AttrEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be a string", d.Code)}
+		}
+		found := false
+		for _, opt := range d.Options {
+			if strings.EqualFold(opt, strings.TrimSpace(s)) {
+				v.TextValue, found = opt, true
+				break
+			}
+		}
+		if !found {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be one of: %s", d.Code, strings.Join(d.Options, ", "))}
+		}
+	default:
+		s, ok := raw.(string)
+		if !ok {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be a string", d.Code)}
+		}
+		s = strings.TrimSpace(s)
+		if s == "" || len([]rune(s)) > 255 {
+			return v, &errAttributeInput{msg: fmt.Sprintf("attribute '%s' must be 1-255 characters", d.Code)}
+		}
+		v.TextValue = s
+	}
+	return v, nil
+}
+
+// attributeValuesMap — значения товара в виде, который принимает applyAttributes.
+func attributeValuesMap(vals []models.ProductAttributeValue) map[string]interface{} {
+	out := make(map[string]interface{}, len(vals))
+	for _, v := range vals {
+		out[v.Attribute.Code] = attributeJSONValue(v)
+	}
+	return out
+}
+
+func attributeJSONValue(v models.ProductAttributeValue) interface{} {
+	switch v.Attribute.Type {
+	case models.AttrNumber:
+		if v.NumberValue != nil {
+			return *v.NumberValue
+		}
+		return nil
+	case models.AttrBool:
+		return v.TextValue == "true"
+	}
+	return v.TextValue
+}
+
+// orderAttributes — значения в порядке атрибутов категории.
+func orderAttributes(tx *gorm.DB) *gorm.DB {
+	return tx.Joins("JOIN attributes ON attributes.id = product_attribute_values.attribute_id").
+		Order("attributes.sort_order asc, attributes.id asc")
+}
+
+// attributeSorts добавляет в allowed сортировки attr.<code> из запроса.
+func attributeSorts(sortParam string, allowed map[string]string) {
+	for _, f := range strings.Split(sortParam, ",") {
+		name := strings.TrimPrefix(strings.TrimSpace(f), "-")
+		code, ok := strings.CutPrefix(name, "attr.")
+		if ok && attrCodeRe.MatchString(code) {
+			allowed[name] = fmt.Sprintf(attrValueSQL, code)
+		}
+	}
+}
+
+func attributesToDTO(vals []models.ProductAttributeValue) []dto.ProductAttributeDTO {
+	sorted := make([]models.ProductAttributeValue, len(vals))
+	copy(sorted, vals)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Attribute.SortOrder < sorted[j].Attribute.SortOrder
+	})
+
+	out := make([]dto.ProductAttributeDTO, 0, len(sorted))
+	for _, v := range sorted {
+		out = append(out, dto.ProductAttributeDTO{
+			Code:  v.Attribute.Code,
+			Name:  v.Attribute.Name,
+			Type:  string(v.Attribute.Type),
+			Unit:  v.Attribute.Unit,
+			Value: attributeJSONValue(v),
+		})
+	}
+	return out
+}
+
+// pricePerServing — цена за порцию по атрибуту servings; nil, если его нет.
+func pricePerServing(vals []models.ProductAttributeValue, price int64) *int64 {
+	for _, v := range vals {
+		if v.Attribute.Code == models.AttrServings && v.NumberValue != nil && *v.NumberValue > 0 {
+			pps := int64(math.Round(float64(price) / *v.NumberValue))
+			return &pps
+		}
+	}
+	return nil
+}
+
+// checkAttributeRules — проверки описания атрибута, которые не выразить тегами.
+func checkAttributeRules(a models.Attribute) string {
+	if !attrCodeRe.MatchString(a.Code) {
+		return "code must contain only lowercase latin letters, digits and '_'"
+	}
+	if a.Type == models.AttrEnum && len(a.Options) == 0 {
+		return "options are required for enum attribute"
+	}
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return "min must not exceed max"
+	}
+	return ""
+}
+
+// ListCategoryAttributes — атрибуты категории для карточки и фильтров витрины.
+func ListCategoryAttributes(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.Select("id").Where("slug = ?", c.Param("slug")).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "category not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	respondAttributes(c, category.ID)
+}
+
+func AdminListAttributes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	respondAttributes(c, uint(id))
+}
+
+func respondAttributes(c *gin.Context, categoryID uint) {
+	var attrs []models.Attribute
+	if err := config.DB.Where("category_id = ?", categoryID).
+		Order("sort_order asc, id asc").
+		Find(&attrs).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.AttributeResponse, 0, len(attrs))
+	for _, a := range attrs {
+		resp = append(resp, attributeToResp(a))
+	}
+	utils.RespondOK(c, resp)
+}
+
+func AdminCreateAttribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.AttributeCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var category models.Category
+	if err := config.DB.Select("id").First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "category not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	attr := models.Attribute{
+		CategoryID: category.ID,
+		Code:       strings.TrimSpace(req.Code),
+		Name:       strings.TrimSpace(req.Name),
+		Type:       models.AttributeType(req.Type),
+		Unit:       strings.TrimSpace(req.Unit),
+		Options:    req.Options,
+		Min:        req.Min,
+		Max:        req.Max,
+		Required:   req.Required,
+		Filterable: req.Filterable,
+		SortOrder:  req.SortOrder,
+	}
+	if attr.Type != models.AttrEnum {
+		attr.Options = nil
+	}
+
+	if msg := checkAttributeRules(attr); msg != "" {
+		utils.RespondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := config.DB.Create(&attr).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "attribute code already exists in category")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, attributeToResp(attr))
+}
+
+func AdminUpdateAttribute(c *gin.Context) {
+	attrID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.AttributeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var attr models.Attribute
+	if err := config.DB.First(&attr, attrID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "attribute not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if req.Type != nil && models.AttributeType(*req.Type) != attr.Type {
+		// сохранённые значения другого типа стали бы нечитаемыми
+		var used int64
+		if err := config.DB.Model(&models.ProductAttributeValue{}).Where("attribute_id = ?", attr.ID).Count(&used).Error; err != nil {
+			utils.RespondError(c, http.StatusInternalServerError, "db error")
+			return
+		}
+		if used > 0 {
+			utils.RespondError(c, http.StatusConflict, "cannot change type of attribute in use")
+			return
+		}
+		attr.Type = models.AttributeType(*req.Type)
+	}
+	if req.Name != nil {
+		attr.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Unit != nil {
+		attr.Unit = strings.TrimSpace(*req.Unit)
+	}
+	if req.Options != nil {
+		attr.Options = *req.Options
+	}
+	if req.Min.Set {
+		attr.Min = req.Min.Value
+	}
+	if req.Max.Set {
+		attr.Max = req.Max.Value
+	}
+	if req.Required != nil {
+		attr.Required = *req.Required
+	}
+	if req.Filterable != nil {
+		attr.Filterable = *req.Filterable
+	}
+	if req.SortOrder != nil {
+		attr.SortOrder = *req.SortOrder
+	}
+	if attr.Type != models.AttrEnum {
+		attr.Options = nil
+	}
+
+	if msg := checkAttributeRules(attr); msg != "" {
+		utils.RespondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := config.DB.Save(&attr).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, attributeToResp(attr))
+}
+
+// AdminDeleteAttribute удаляет атрибут вместе со значениями у товаров.
+func AdminDeleteAttribute(c *gin.Context) {
+	attrID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	res := config.DB.Unscoped().Delete(&models.Attribute{}, attrID)
+	if res.Error != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.RespondError(c, http.StatusNotFound, "attribute not found")
+		return
+	}
+	utils.RespondOK(c, gin.H{"deleted": true})
+}
+
+func attributeToResp(a models.Attribute) dto.AttributeResponse {
+	options := a.Options
+	if options == nil {
+		options = []string{}
+	}
+	return dto.AttributeResponse{
+		ID:         a.ID,
+		CategoryID: a.CategoryID,
+		Code:       a.Code,
+		Name:       a.Name,
+		Type:       string(a.Type),
+		Unit:       a.Unit,
+		Options:    options,
+		Min:        a.Min,
+		Max:        a.Max,
+		Required:   a.Required,
+		Filterable: a.Filterable,
+		SortOrder:  a.SortOrder,
+	}
+}
@@ -121,6 +121,9 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// гостевая корзина переезжает к пользователю; ошибка слияния не должна ломать логин
+	_ = mergeGuestCart(c.GetHeader(CartTokenHeader), user.ID)
+
 	utils.RespondOK(c, dto.TokenPairResponse{
 		AccessToken:  access,
 		RefreshToken: pass,
@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fefoOrder — первыми уходят партии с ближайшим сроком годности (FEFO),
+// партии без срока — последними.
+const fefoOrder = "expires_at asc nulls last, id asc"
+
+func batchScope(db *gorm.DB, productID uint, variantID *uint) *gorm.DB {
+	db = db.Model(&models.StockBatch{}).Where("product_id = ?", productID)
+	if variantID != nil {
+		return db.Where("variant_id = ?", *variantID)
+	}
+	return db.Where("variant_id IS NULL")
+}
+
+// applyBatches раскладывает движение журнала по партиям:
+//   - движение на конкретную партию (m.BatchID) меняет только её;
+//   - расход списывается с партий по FEFO, остаток без партий допустим
+//     (товар, принятый до учёта партий);
+//   - продажа не берёт просроченные партии: они уходят только списанием,
+//     и продать можно не больше, чем есть в живых партиях и вне партий;
+//   - возврат по заказу возвращается в те партии, из которых был продан;
+//   - прочий приход без партии в партиях не учитывается.
+func applyBatches(tx *gorm.DB, m stockMove, rec models.StockMovement) error {
+	if m.BatchID != nil {
+		var b models.StockBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", m.ProductID).
+			First(&b, *m.BatchID).Error; err != nil {
+			return err
+		}
+		if b.Quantity+m.Delta < 0 {
+			return errInsufficientStock
+		}
+		return allocateBatch(tx, rec.ID, b, m.Delta)
+	}
+
+	if m.Delta < 0 {
+		q := batchScope(tx, m.ProductID, m.VariantID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("quantity > 0")
+		if m.Kind == models.StockSale {
+			q = q.Where("(expires_at IS NULL OR expires_at > ?)", time.Now())
+		}
+		var batches []models.StockBatch
+		if err := q.Order(fefoOrder).Find(&batches).Error; err != nil {
+			return err
+		}
+		left := -m.Delta
+		for _, b := range batches {
+			if left == 0 {
+				break
+			}
+			take := b.Quantity
+			if take > left {
+				take = left
+			}
+			if err := allocateBatch(tx, rec.ID, b, -take); err != nil {
+				return err
+			}
+			left -= take
+		}
+		if m.Kind == models.StockSale && left > 0 {
+			// недостающее берётся из остатка вне партий; если после продажи
+			// остатка не хватает на оставшиеся партии, продали бы просроченное
+			var inBatches int64
+			if err := batchScope(tx, m.ProductID, m.VariantID).
+				Select("COALESCE(SUM(quantity), 0)").
+				Scan(&inBatches).Error; err != nil {
+				return err
+			}
+			if int64(rec.Balance) < inBatches {
+				return errInsufficientStock
+			}
+		}
+		return nil
+	}
+
+	if m.Kind == models.StockReturn && m.Reference != "" {
+		var sold []models.StockBatchAllocation
+		if err := tx.Model(&models.StockBatchAllocation{}).
+			Joins("JOIN stock_movements sm ON sm.id = stock_batch_allocations.movement_id").
+			Where("sm.reference = ? AND sm.kind = ? AND sm.product_id = ?", m.Reference, models.StockSale, m.ProductID).
+			Where("stock_batch_allocations.quantity < 0").
+			Order("stock_batch_allocations.id desc").
+			Find(&sold).Error; err != nil {
+			return err
+		}
+		left := m.Delta
+		for _, a := range sold {
+			if left == 0 {
+				break
+			}
+			var b models.StockBatch
+			if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, a.BatchID).Error; err != nil {
+				return err
+			}
+			if !sameID(b.VariantID, m.VariantID) {
+				continue
+			}
+			back := -a.Quantity
+			if back > left {
+				back = left
+			}
+			if err := allocateBatch(tx, rec.ID, b, back); err != nil {
+				return err
+			}
+			left -= back
+		}
+	}
+
+	return nil
+}
+
+func allocateBatch(tx *gorm.DB, movementID uint, b models.StockBatch, qty int) error {
+	if err := tx.Unscoped().Model(&models.StockBatch{}).
+		Where("id = ?", b.ID).
+		UpdateColumn("quantity", gorm.Expr("quantity + ?", qty)).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.StockBatchAllocation{
+		MovementID: movementID,
+		BatchID:    b.ID,
+		Quantity:   qty,
+	}).Error
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func AdminListBatches(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	db := config.DB.Model(&models.StockBatch{}).Where("product_id = ?", id)
+	if c.Query("all") != "true" {
+		db = db.Where("quantity > 0")
+	}
+
+	var batches []models.StockBatch
+	if err := db.Order(fefoOrder).Find(&batches).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.StockBatchResponse, 0, len(batches))
+	for _, b := range batches {
+		resp = append(resp, batchToResp(b, "", ""))
+	}
+	utils.RespondOK(c, resp)
+}
+
+// AdminReceiveBatch — приход новой партии: создаёт партию и проводит приход по журналу.
+func AdminReceiveBatch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.StockBatchCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var batch models.StockBatch
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var p models.Product
+		if err := tx.Preload("Tastes").First(&p, id).Error; err != nil {
+			return err
+		}
+		if len(p.Tastes) > 0 && req.VariantID == nil {
+			return &errVariantInput{msg: "variant_id is required for product with variants"}
+		}
+
+		batch = models.StockBatch{
+			ProductID:       p.ID,
+			VariantID:       req.VariantID,
+			LotNumber:       strings.TrimSpace(req.LotNumber),
+			InitialQuantity: req.Quantity,
+			ExpiresAt:       req.ExpiresAt,
+			ReceivedAt:      time.Now(),
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+
+		reason := strings.TrimSpace(req.Reason)
+		if reason == "" {
+			reason = "приход партии " + batch.LotNumber
+		}
+		if _, err := moveStock(tx, stockMove{
+			ProductID: p.ID,
+			VariantID: req.VariantID,
+			BatchID:   &batch.ID,
+			Kind:      models.StockReceipt,
+			Delta:     req.Quantity,
+			Reason:    reason,
+			ActorID:   currentUserID(c),
+			Reference: fmt.Sprintf("batch:%d", batch.ID),
+		}); err != nil {
+			return err
+		}
+		batch.Quantity = req.Quantity
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product or variant not found")
+			return
+		}
+		if ve, ok := isVariantInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ve.Error())
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, batchToResp(batch, "", ""))
+}
+
+// AdminWriteOffBatch списывает партию (например, просроченную) целиком или частично.
+func AdminWriteOffBatch(c *gin.Context) {
+	batchID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.StockBatchWriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var batch models.StockBatch
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&batch, batchID).Error; err != nil {
+			return err
+		}
+		qty := batch.Quantity
+		if req.Quantity != nil {
+			qty = *req.Quantity
+		}
+		if qty <= 0 || qty > batch.Quantity {
+			return errInsufficientStock
+		}
+
+		if _, err := moveStock(tx, stockMove{
+			ProductID: batch.ProductID,
+			VariantID: batch.VariantID,
+			BatchID:   &batch.ID,
+			Kind:      models.StockWriteOff,
+			Delta:     -qty,
+			Reason:    strings.TrimSpace(req.Reason),
+			ActorID:   currentUserID(c),
+			Reference: fmt.Sprintf("batch:%d", batch.ID),
+		}); err != nil {
+			return err
+		}
+		batch.Quantity -= qty
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "batch not found")
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			utils.RespondError(c, http.StatusConflict, "not enough quantity in batch")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, batchToResp(batch, "", ""))
+}
+
+// AdminExpiringBatches — отчёт по партиям, у которых срок годности истекает
+// в ближайшие days дней (по умолчанию 30), включая уже просроченные.
+func AdminExpiringBatches(c *gin.Context) {
+	days := 30
+	if v := c.Query("days"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 3650 {
+			days = n
+		}
+	}
+
+	type row struct {
+		models.StockBatch
+		ProductName string
+		VariantName string
+	}
+
+	var rows []row
+	if err := config.DB.Model(&models.StockBatch{}).
+		Select("stock_batches.*, products.name AS product_name, COALESCE(product_tastes.name, '') AS variant_name").
+		Joins("JOIN products ON products.id = stock_batches.product_id").
+		Joins("LEFT JOIN product_tastes ON product_tastes.id = stock_batches.variant_id").
+		Where("stock_batches.quantity > 0").
+		Where("stock_batches.expires_at IS NOT NULL AND stock_batches.expires_at <= ?", time.Now().AddDate(0, 0, days)).
+		Order("stock_batches.expires_at asc, stock_batches.id asc").
+		Scan(&rows).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.StockBatchResponse, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, batchToResp(r.StockBatch, r.ProductName, r.VariantName))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"days":  days,
+		"items": resp,
+	})
+}
+
+func batchToResp(b models.StockBatch, productName, variantName string) dto.StockBatchResponse {
+	resp := dto.StockBatchResponse{
+		ID:              b.ID,
+		ProductID:       b.ProductID,
+		ProductName:     productName,
+		VariantID:       b.VariantID,
+		VariantName:     variantName,
+		LotNumber:       b.LotNumber,
+		InitialQuantity: b.InitialQuantity,
+		Quantity:        b.Quantity,
+		ExpiresAt:       b.ExpiresAt,
+		ReceivedAt:      b.ReceivedAt,
+	}
+	if b.ExpiresAt != nil {
+		left := int(math.Floor(time.Until(*b.ExpiresAt).Hours() / 24))
+		resp.DaysLeft = &left
+	}
+	return resp
+}
@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/search"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+var errBrandNotFound = errors.New("brand not found")
+
+// loadBrand проверяет, что бренд существует; nil id — товар без бренда.
+func loadBrand(db *gorm.DB, id *uint) (*models.Brand, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var b models.Brand
+	if err := db.First(&b, *id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errBrandNotFound
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+func respondBrandError(c *gin.Context, err error) {
+	if errors.Is(err, errBrandNotFound) {
+		utils.RespondError(c, http.StatusBadRequest, "brand not found")
+		return
+	}
+	utils.RespondError(c, http.StatusInternalServerError, "db error")
+}
+
+// ListBrands — активные бренды с числом активных товаров.
+func ListBrands(c *gin.Context) {
+	type row struct {
+		models.Brand
+		ProductCount int64
+	}
+
+	var rows []row
+	if err := config.DB.Model(&models.Brand{}).
+		Select(`brands.*, (SELECT COUNT(*) FROM products
+			WHERE products.brand_id = brands.id AND products.is_active AND products.deleted_at IS NULL) AS product_count`).
+		Where("brands.is_active = ?", true).
+		Order("brands.name asc").
+		Scan(&rows).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.BrandResponse, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, brandToResp(r.Brand, r.ProductCount))
+	}
+	utils.RespondOK(c, resp)
+}
+
+// GetBrand — страница бренда: данные бренда и его товары с фильтрами и фасетами,
+// как в каталоге.
+func GetBrand(c *gin.Context) {
+	var brand models.Brand
+	if err := config.DB.Where("slug = ? AND is_active = ?", c.Param("slug"), true).First(&brand).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "brand not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	f, err := parseProductFilter(c)
+	if err != nil {
+		respondFilterError(c, err)
+		return
+	}
+	f.BrandIDs = []uint{brand.ID}
+
+	page, err := productListing(c, f)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, dto.BrandPageResponse{
+		ProductPage: page,
+		Brand:       brandToResp(brand, page.Total),
+	})
+}
+
+func AdminListBrands(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.Brand{})
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		db = db.Where("name ILIKE ? OR slug ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	type row struct {
+		models.Brand
+		ProductCount int64
+		TrashedCount int64
+	}
+
+	var rows []row
+	if err := db.
+		Select(`brands.*, (SELECT COUNT(*) FROM products
+			WHERE products.brand_id = brands.id AND products.deleted_at IS NULL) AS product_count,
+			(SELECT COUNT(*) FROM products
+			WHERE products.brand_id = brands.id AND products.deleted_at IS NOT NULL) AS trashed_count`).
+		Order("name asc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Scan(&rows).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.BrandResponse, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, adminBrandToResp(r.Brand, r.ProductCount, r.TrashedCount))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminGetBrand(c *gin.Context) {
+	brandID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var brand models.Brand
+	if err := config.DB.First(&brand, brandID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "brand not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	live, trashed, err := brandProductCounts(config.DB, brand.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, adminBrandToResp(brand, live, trashed))
+}
+
+func AdminCreateBrand(c *gin.Context) {
+	var req dto.BrandCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	brand := models.Brand{
+		Name:        strings.TrimSpace(req.Name),
+		Slug:        strings.TrimSpace(req.Slug),
+		Description: req.Description,
+		LogoURL:     req.LogoURL,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		brand.IsActive = *req.IsActive
+	}
+
+	if err := config.DB.Create(&brand).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug already exists")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, adminBrandToResp(brand, 0, 0))
+}
+
+func AdminUpdateBrand(c *gin.Context) {
+	brandID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.BrandUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var brand models.Brand
+	if err := config.DB.First(&brand, brandID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "brand not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	renamed := req.Name != nil && strings.TrimSpace(*req.Name) != brand.Name
+	if req.Name != nil {
+		brand.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Slug != nil {
+		brand.Slug = strings.TrimSpace(*req.Slug)
+	}
+	if req.Description != nil {
+		brand.Description = *req.Description
+	}
+	if req.LogoURL != nil {
+		brand.LogoURL = *req.LogoURL
+	}
+	if req.IsActive != nil {
+		brand.IsActive = *req.IsActive
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&brand).Error; err != nil {
+			return err
+		}
+		// название бренда входит в поисковый индекс товаров
+		if renamed {
+			return search.RefreshBrand(tx, brand.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug already exists")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	live, trashed, _ := brandProductCounts(config.DB, brand.ID)
+
+	utils.RespondOK(c, adminBrandToResp(brand, live, trashed))
+}
+
+// AdminDeleteBrand удаляет бренд, если к нему не привязаны товары.
+func AdminDeleteBrand(c *gin.Context) {
+	brandID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var brand models.Brand
+	if err := config.DB.First(&brand, brandID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "brand not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	// товары в корзине тоже держат бренд: при окончательном удалении их нельзя оставить без него
+	live, trashed, err := brandProductCounts(config.DB, brand.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	if live+trashed > 0 {
+		msg := fmt.Sprintf("Невозможно удалить бренд '%s': к нему привязаны товары", brand.Name)
+		if live == 0 {
+			msg = fmt.Sprintf("Невозможно удалить бренд '%s': к нему привязаны товары в корзине", brand.Name)
+		}
+		utils.RespondError(c, http.StatusConflict, msg,
+			gin.H{"product_count": live, "trashed_count": trashed})
+		return
+	}
+
+	if err := config.DB.Unscoped().Delete(&brand).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, gin.H{"deleted": true})
+}
+
+// brandProductCounts — число товаров бренда: живых и лежащих в корзине.
+func brandProductCounts(db *gorm.DB, brandID uint) (live, trashed int64, err error) {
+	var row struct {
+		Live    int64
+		Trashed int64
+	}
+	err = db.Unscoped().Model(&models.Product{}).
+		Select(`COUNT(*) FILTER (WHERE deleted_at IS NULL) AS live,
+			COUNT(*) FILTER (WHERE deleted_at IS NOT NULL) AS trashed`).
+		Where("brand_id = ?", brandID).
+		Scan(&row).Error
+	return row.Live, row.Trashed, err
+}
+
+func adminBrandToResp(b models.Brand, productCount, trashedCount int64) dto.BrandResponse {
+	resp := brandToResp(b, productCount)
+	resp.TrashedCount = &trashedCount
+	return resp
+}
+
+func brandToResp(b models.Brand, productCount int64) dto.BrandResponse {
+	return dto.BrandResponse{
+		ID:           b.ID,
+		Name:         b.Name,
+		Slug:         b.Slug,
+		Description:  b.Description,
+		LogoURL:      b.LogoURL,
+		IsActive:     b.IsActive,
+		ProductCount: productCount,
+	}
+}
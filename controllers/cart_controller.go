@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const CartTokenHeader = "X-Cart-Token"
+
+var errCartNotFound = errors.New("cart not found")
+
+// loadCart ищет корзину текущего покупателя: по userID, если он залогинен,
+// иначе по гостевому токену из заголовка X-Cart-Token.
+// При create=true отсутствующая корзина создаётся.
+func loadCart(c *gin.Context, create bool) (*models.Cart, error) {
+	var cart models.Cart
+
+	if uid, ok := c.Get("userID"); ok {
+		userID := uid.(uint)
+		err := config.DB.Where("user_id = ?", userID).First(&cart).Error
+		if err == nil {
+			return &cart, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if !create {
+			return nil, errCartNotFound
+		}
+		cart = models.Cart{UserID: &userID}
+		if err := config.DB.Create(&cart).Error; err != nil {
+			return nil, err
+		}
+		return &cart, nil
+	}
+
+	if token := strings.TrimSpace(c.GetHeader(CartTokenHeader)); token != "" {
+		err := config.DB.Where("token = ? AND user_id IS NULL", token).First(&cart).Error
+		if err == nil {
+			return &cart, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if !create {
+		return nil, errCartNotFound
+	}
+
+	token, err := utils.NewCartToken()
+	if err != nil {
+		return nil, err
+	}
+	cart = models.Cart{Token: &token}
+	if err := config.DB.Create(&cart).Error; err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// findProductTaste ищет вкус товара по названию без учёта регистра.
+func findProductTaste(p models.Product, taste string) (models.ProductTaste, bool) {
+	if taste == "" {
+		return models.ProductTaste{}, false
+	}
+	for _, t := range p.Tastes {
+		if strings.EqualFold(t.Name, taste) {
+			return t, true
+		}
+	}
+	return models.ProductTaste{}, false
+}
+
+// lineStock — сколько можно продать: остаток варианта, а для товара без вкусов — остаток товара.
+func lineStock(p models.Product, taste string) int {
+	if v, ok := findProductTaste(p, taste); ok {
+		return v.Stock
+	}
+	return p.Stock
+}
+
+// checkCartLine проверяет, можно ли сейчас купить qty единиц товара с этим вкусом.
+// Возвращает пустую строку, если всё в порядке.
+func checkCartLine(p models.Product, taste string, qty int) string {
+	if p.ID == 0 || !p.IsActive {
+		return "товар недоступен"
+	}
+	if taste == "" && len(p.Tastes) > 0 {
+		return "выберите вкус"
+	}
+	if taste != "" {
+		v, ok := findProductTaste(p, taste)
+		if !ok || !v.IsActive {
+			return "вкус недоступен"
+		}
+	}
+	stock := lineStock(p, taste)
+	if stock <= 0 {
+		return "нет в наличии"
+	}
+	if stock < qty {
+		return fmt.Sprintf("в наличии только %d шт.", stock)
+	}
+	return ""
+}
+
+func preloadCartItems(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Items", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id asc")
+		}).
+		Preload("Items.Product.Images", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("is_primary desc, sort_order asc")
+		}).
+		Preload("Items.Product.Tastes")
+}
+
+// cartToResp пересчитывает корзину по живым ценам и остаткам товаров.
+func cartToResp(cart models.Cart) dto.CartResponse {
+	resp := dto.CartResponse{
+		ID:    cart.ID,
+		Items: []dto.CartItemResponse{},
+	}
+	if cart.UserID == nil && cart.Token != nil {
+		resp.Token = *cart.Token
+	}
+
+	for _, it := range cart.Items {
+		p := it.Product
+		price := productUnitPrice(p, it.Taste)
+
+		item := dto.CartItemResponse{
+			ID:         it.ID,
+			ProductID:  it.ProductID,
+			Name:       p.Name,
+			Slug:       p.Slug,
+			Taste:      it.Taste,
+			Quantity:   it.Quantity,
+			Price:      price,
+			PriceAtAdd: it.PriceAtAdd,
+			LineTotal:  price * int64(it.Quantity),
+			Stock:      lineStock(p, it.Taste),
+			Available:  true,
+		}
+		if len(p.Images) > 0 {
+			item.ImageURL = p.Images[0].URL
+		}
+
+		if problem := checkCartLine(p, it.Taste, it.Quantity); problem != "" {
+			item.Available = false
+			item.Problem = problem
+			resp.HasProblems = true
+		} else {
+			resp.TotalQty += it.Quantity
+			resp.Total += item.LineTotal
+		}
+
+		resp.Items = append(resp.Items, item)
+	}
+
+	return resp
+}
+
+func respondCart(c *gin.Context, cartID uint) {
+	var cart models.Cart
+	if err := preloadCartItems(config.DB).First(&cart, cartID).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, cartToResp(cart))
+}
+
+func GetCart(c *gin.Context) {
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondOK(c, dto.CartResponse{Items: []dto.CartItemResponse{}})
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	respondCart(c, cart.ID)
+}
+
+func AddCartItem(c *gin.Context) {
+	var req dto.CartItemAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var p models.Product
+	if err := config.DB.Preload("Tastes").First(&p, req.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	req.Taste = strings.TrimSpace(req.Taste)
+	if t, ok := findProductTaste(p, req.Taste); ok {
+		req.Taste = t.Name
+	}
+
+	cart, err := loadCart(c, true)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var item models.CartItem
+	err = config.DB.Where("cart_id = ? AND product_id = ? AND taste = ?", cart.ID, p.ID, req.Taste).First(&item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	qty := item.Quantity + req.Quantity
+	if problem := checkCartLine(p, req.Taste, qty); problem != "" {
+		utils.RespondError(c, http.StatusConflict, problem, gin.H{"stock": lineStock(p, req.Taste)})
+		return
+	}
+
+	if item.ID == 0 {
+		item = models.CartItem{
+			CartID:    cart.ID,
+			ProductID: p.ID,
+			Taste:     req.Taste,
+		}
+	}
+	item.Quantity = qty
+	item.PriceAtAdd = productUnitPrice(p, req.Taste)
+
+	if err := config.DB.Save(&item).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	respondCart(c, cart.ID)
+}
+
+func UpdateCartItem(c *gin.Context) {
+	var req dto.CartItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	itemID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "cart not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var item models.CartItem
+	if err := config.DB.
+		Preload("Product.Tastes").
+		Where("cart_id = ?", cart.ID).
+		First(&item, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "cart item not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if problem := checkCartLine(item.Product, item.Taste, req.Quantity); problem != "" {
+		utils.RespondError(c, http.StatusConflict, problem, gin.H{"stock": lineStock(item.Product, item.Taste)})
+		return
+	}
+
+	if err := config.DB.Model(&item).Update("quantity", req.Quantity).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	respondCart(c, cart.ID)
+}
+
+func RemoveCartItem(c *gin.Context) {
+	itemID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "cart not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if err := config.DB.Unscoped().
+		Where("cart_id = ?", cart.ID).
+		Delete(&models.CartItem{}, itemID).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	respondCart(c, cart.ID)
+}
+
+func ClearCart(c *gin.Context) {
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondOK(c, dto.CartResponse{Items: []dto.CartItemResponse{}})
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if err := config.DB.Unscoped().Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	respondCart(c, cart.ID)
+}
+
+// mergeGuestCart переносит позиции гостевой корзины в корзину пользователя
+// (одинаковые товар+вкус складываются) и удаляет гостевую корзину.
+func mergeGuestCart(token string, userID uint) error {
+	if token == "" {
+		return nil
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var guest models.Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			Where("token = ? AND user_id IS NULL", token).
+			First(&guest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var cart models.Cart
+		err = tx.Where("user_id = ?", userID).First(&cart).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// у пользователя корзины ещё нет — просто забираем гостевую
+			return tx.Model(&guest).Updates(map[string]interface{}{
+				"user_id": userID,
+				"token":   nil,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, gi := range guest.Items {
+			var item models.CartItem
+			err := tx.Where("cart_id = ? AND product_id = ? AND taste = ?", cart.ID, gi.ProductID, gi.Taste).
+				First(&item).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if item.ID == 0 {
+				item = models.CartItem{
+					CartID:     cart.ID,
+					ProductID:  gi.ProductID,
+					Taste:      gi.Taste,
+					PriceAtAdd: gi.PriceAtAdd,
+				}
+			}
+			item.Quantity += gi.Quantity
+			if err := tx.Save(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("cart_id = ?", guest.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&guest).Error
+	})
+}
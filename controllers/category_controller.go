@@ -4,6 +4,7 @@ import (
 	"clen_shop/config"
 	"clen_shop/dto"
 	"clen_shop/models"
+	"clen_shop/search"
 	"clen_shop/utils"
 	"clen_shop/validators"
 	"errors"
@@ -13,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateCategory(c *gin.Context) {
@@ -40,23 +42,46 @@ func CreateCategory(c *gin.Context) {
 		ImageURL:    req.ImageURL,
 	}
 
-	if err := config.DB.Create(&category).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, 0, req.ParentID); err != nil {
+			return err
+		}
+		if req.SortOrder != nil {
+			category.SortOrder = *req.SortOrder
+		} else {
+			next, err := nextCategorySortOrder(tx, req.ParentID)
+			if err != nil {
+				return err
+			}
+			category.SortOrder = next
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		if he, ok := isCategoryHierarchyErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, he.Error())
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug already exists")
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "db error")
 		return
 	}
+	invalidateCategoryTree()
 
-	utils.RespondCreated(c, dto.CategoryResponse{
-		ID:          category.ID,
-		Name:        category.Name,
-		Slug:        category.Slug,
-		Description: category.Description,
-		ImageURL:    category.ImageURL,
-		ParentID:    category.ParentID,
-	})
+	utils.RespondCreated(c, categoryToResp(category))
 
 }
 
 func UpdateCategory(c *gin.Context) {
+	categoryID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
 	var req dto.CategoryUpdateRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -74,7 +99,7 @@ func UpdateCategory(c *gin.Context) {
 	}
 
 	var category models.Category
-	if err := config.DB.First(&category, c.Param("id")).Error; err != nil {
+	if err := config.DB.First(&category, categoryID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.RespondError(c, http.StatusNotFound, "category not found")
 			return
@@ -83,6 +108,7 @@ func UpdateCategory(c *gin.Context) {
 		return
 	}
 
+	renamed := req.Name != nil && *req.Name != category.Name
 	if req.Name != nil {
 		category.Name = *req.Name
 	}
@@ -99,21 +125,49 @@ func UpdateCategory(c *gin.Context) {
 		category.ImageURL = *req.ImageURL
 	}
 
-	category.ParentID = req.ParentID
+	if req.SortOrder != nil {
+		category.SortOrder = *req.SortOrder
+	}
 
-	if err := config.DB.Save(&category).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// родитель меняется только явно: отсутствие parent_id больше не делает категорию корневой
+		if req.ParentID.Set && !sameID(req.ParentID.Value, category.ParentID) {
+			if err := checkCategoryParent(tx, category.ID, req.ParentID.Value); err != nil {
+				return err
+			}
+			category.ParentID = req.ParentID.Value
+			if req.SortOrder == nil {
+				next, err := nextCategorySortOrder(tx, category.ParentID)
+				if err != nil {
+					return err
+				}
+				category.SortOrder = next
+			}
+		}
+		if err := tx.Omit(clause.Associations).Save(&category).Error; err != nil {
+			return err
+		}
+		// название категории входит в поисковый индекс товаров
+		if renamed {
+			return search.RefreshCategory(tx, category.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		if he, ok := isCategoryHierarchyErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, he.Error())
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug already exists")
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "db error")
 		return
 	}
+	invalidateCategoryTree()
 
-	utils.RespondOK(c, dto.CategoryResponse{
-		ID:          category.ID,
-		Name:        category.Name,
-		Slug:        category.Slug,
-		Description: category.Description,
-		ImageURL:    category.ImageURL,
-		ParentID:    category.ParentID,
-	})
+	utils.RespondOK(c, categoryToResp(category))
 
 }
 
@@ -151,11 +205,28 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	// безопасно удаляем — привязанных товаров нет
-	if err := config.DB.Unscoped().Delete(&category).Error; err != nil {
+	// подкатегории без родителя пропали бы из дерева
+	var children int64
+	if err := config.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Ошибка поиска категории")
+		return
+	}
+	if children > 0 {
+		utils.RespondError(
+			c,
+			http.StatusBadRequest,
+			fmt.Sprintf("Невозможно удалить категорию '%s': у неё есть подкатегории", category.Name),
+			gin.H{"children": children},
+		)
+		return
+	}
+
+	// привязанных товаров нет — переносим в корзину (/admin/trash)
+	if err := config.DB.Delete(&category).Error; err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Ошибка удаления категории")
 		return
 	}
+	invalidateCategoryTree()
 
 	utils.RespondOK(c, gin.H{"deleted": true})
 }
@@ -175,16 +246,44 @@ func GetCategory(c *gin.Context) {
 		return
 	}
 
-	utils.RespondOK(c, dto.CategoryResponse{
-		ID:          category.ID,
-		Name:        category.Name,
-		Slug:        category.Slug,
-		Description: category.Description,
-		ImageURL:    category.ImageURL,
-		ParentID:    category.ParentID,
-		Products:    category.Products,
-	})
+	crumbs, err := categoryBreadcrumbs(config.DB, category.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var children []models.Category
+	if err := config.DB.Where("parent_id = ?", category.ID).Order(categoryOrder).Find(&children).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
 
+	// товары — через общий конвейер каталога: q, сортировка, фасеты и т.д.
+	// работают так же, как в /products; категория берётся из пути
+	f, err := parseProductFilter(c)
+	if err != nil {
+		respondFilterError(c, err)
+		return
+	}
+	f.CategoryID = &category.ID
+
+	products, err := productListing(c, f)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := dto.CategoryPageResponse{
+		CategoryResponse: categoryToResp(category),
+		Subcategories:    make([]dto.CategoryResponse, 0, len(children)),
+		Products:         products,
+	}
+	resp.Breadcrumbs = crumbs
+	for _, ch := range children {
+		resp.Subcategories = append(resp.Subcategories, categoryToResp(ch))
+	}
+
+	utils.RespondOK(c, resp)
 }
 
 func ListCategories(c *gin.Context) {
@@ -228,3 +327,15 @@ func ListCategories(c *gin.Context) {
 	})
 
 }
+
+func categoryToResp(cat models.Category) dto.CategoryResponse {
+	return dto.CategoryResponse{
+		ID:          cat.ID,
+		Name:        cat.Name,
+		Slug:        cat.Slug,
+		Description: cat.Description,
+		ImageURL:    cat.ImageURL,
+		ParentID:    cat.ParentID,
+		SortOrder:   cat.SortOrder,
+	}
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// categoryOrder — порядок показа категорий среди соседей.
+const categoryOrder = "sort_order asc, name asc, id asc"
+
+// categoryMaxDepth — максимальная глубина дерева (корень — уровень 1).
+func categoryMaxDepth() int {
+	if s := os.Getenv("CATEGORY_MAX_DEPTH"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+// errCategoryHierarchy — изменение нарушило бы структуру дерева.
+type errCategoryHierarchy struct {
+	msg string
+}
+
+func (e *errCategoryHierarchy) Error() string { return e.msg }
+
+func isCategoryHierarchyErr(err error) (*errCategoryHierarchy, bool) {
+	var he *errCategoryHierarchy
+	ok := errors.As(err, &he)
+	return he, ok
+}
+
+// checkCategoryParent проверяет, что категорию id (0 — новая) можно
+// поместить под parentID: родитель существует, это не она сама и не её
+// потомок, и дерево не станет глубже categoryMaxDepth. Вызывается в
+// транзакции: до её конца изменения дерева идут по очереди, иначе два
+// встречных переноса (A под B и B под A) прошли бы проверку оба.
+func checkCategoryParent(db *gorm.DB, id uint, parentID *uint) error {
+	if err := db.Exec("SELECT pg_advisory_xact_lock(hashtext('categories'))").Error; err != nil {
+		return err
+	}
+
+	height := 1
+	if id != 0 {
+		if err := db.Raw(`WITH RECURSIVE sub AS (
+			SELECT id, 1 AS depth FROM categories WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, sub.depth + 1 FROM categories c JOIN sub ON c.parent_id = sub.id
+			WHERE c.deleted_at IS NULL AND sub.depth < 100
+		) SELECT COALESCE(MAX(depth), 1) FROM sub`, id).Scan(&height).Error; err != nil {
+			return err
+		}
+	}
+
+	if parentID == nil {
+		if height > categoryMaxDepth() {
+			return &errCategoryHierarchy{msg: fmt.Sprintf("category tree cannot be deeper than %d levels", categoryMaxDepth())}
+		}
+		return nil
+	}
+
+	if *parentID == id {
+		return &errCategoryHierarchy{msg: "category cannot be its own parent"}
+	}
+
+	var parent models.Category
+	if err := db.Select("id").First(&parent, *parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &errCategoryHierarchy{msg: "parent category not found"}
+		}
+		return err
+	}
+
+	if id != 0 {
+		var loops int64
+		if err := db.Raw(`SELECT COUNT(*) FROM (`+categoryDescendantsSQL+`) d WHERE d.id = ?`, id, *parentID).
+			Scan(&loops).Error; err != nil {
+			return err
+		}
+		if loops > 0 {
+			return &errCategoryHierarchy{msg: "category cannot be moved under its own subcategory"}
+		}
+	}
+
+	crumbs, err := categoryBreadcrumbs(db, *parentID)
+	if err != nil {
+		return err
+	}
+	if len(crumbs)+height > categoryMaxDepth() {
+		return &errCategoryHierarchy{msg: fmt.Sprintf("category tree cannot be deeper than %d levels", categoryMaxDepth())}
+	}
+	return nil
+}
+
+func siblingsScope(db *gorm.DB, parentID *uint) *gorm.DB {
+	db = db.Model(&models.Category{})
+	if parentID == nil {
+		return db.Where("parent_id IS NULL")
+	}
+	return db.Where("parent_id = ?", *parentID)
+}
+
+// nextCategorySortOrder — позиция в конце списка соседей.
+func nextCategorySortOrder(db *gorm.DB, parentID *uint) (int, error) {
+	var next int
+	err := siblingsScope(db, parentID).Select("COALESCE(MAX(sort_order) + 1, 0)").Scan(&next).Error
+	return next, err
+}
+
+// placeCategory ставит категорию на позицию position среди детей parentID
+// (nil position — в конец) и перенумеровывает соседей по порядку.
+func placeCategory(tx *gorm.DB, cat *models.Category, parentID *uint, position *int) error {
+	var siblings []models.Category
+	if err := siblingsScope(tx, parentID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id <> ?", cat.ID).
+		Order(categoryOrder).
+		Find(&siblings).Error; err != nil {
+		return err
+	}
+
+	pos := len(siblings)
+	if position != nil && *position >= 0 && *position < pos {
+		pos = *position
+	}
+
+	ordered := make([]models.Category, 0, len(siblings)+1)
+	ordered = append(ordered, siblings[:pos]...)
+	ordered = append(ordered, *cat)
+	ordered = append(ordered, siblings[pos:]...)
+
+	for i, s := range ordered {
+		if s.ID == cat.ID {
+			cat.ParentID = parentID
+			cat.SortOrder = i
+			if err := tx.Model(&models.Category{}).Where("id = ?", cat.ID).
+				Updates(map[string]interface{}{"parent_id": parentID, "sort_order": i}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if s.SortOrder != i {
+			if err := tx.Model(&models.Category{}).Where("id = ?", s.ID).
+				UpdateColumn("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AdminMoveCategory переносит категорию к другому родителю и/или меняет её
+// место среди соседей. parent_id: null — в корень, не передан — прежний родитель.
+func AdminMoveCategory(c *gin.Context) {
+	categoryID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.CategoryMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var category models.Category
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&category, categoryID).Error; err != nil {
+			return err
+		}
+
+		parentID := category.ParentID
+		if req.ParentID.Set {
+			parentID = req.ParentID.Value
+			if err := checkCategoryParent(tx, category.ID, parentID); err != nil {
+				return err
+			}
+		}
+		return placeCategory(tx, &category, parentID, req.Position)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "category not found")
+			return
+		}
+		if he, ok := isCategoryHierarchyErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, he.Error())
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	invalidateCategoryTree()
+
+	utils.RespondOK(c, categoryToResp(category))
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// categoryTreeTTL — страховка на случай записи в обход обработчиков
+// (другой инстанс, ручной SQL); обычно кэш сбрасывается сразу при изменениях.
+const categoryTreeTTL = 10 * time.Minute
+
+var categoryTreeCache struct {
+	sync.Mutex
+	tree     []dto.CategoryTreeNode
+	loadedAt time.Time
+}
+
+// invalidateCategoryTree сбрасывает кэш дерева. Вызывается после записи
+// категорий и товаров (меняются узлы или счётчики).
+func invalidateCategoryTree() {
+	categoryTreeCache.Lock()
+	categoryTreeCache.tree = nil
+	categoryTreeCache.Unlock()
+}
+
+func cachedCategoryTree() ([]dto.CategoryTreeNode, error) {
+	categoryTreeCache.Lock()
+	defer categoryTreeCache.Unlock()
+
+	if categoryTreeCache.tree != nil && time.Since(categoryTreeCache.loadedAt) < categoryTreeTTL {
+		return categoryTreeCache.tree, nil
+	}
+	tree, err := buildCategoryTree()
+	if err != nil {
+		return nil, err
+	}
+	categoryTreeCache.tree = tree
+	categoryTreeCache.loadedAt = time.Now()
+	return tree, nil
+}
+
+// buildCategoryTree собирает дерево из плоского списка. ProductCount узла —
+// активные товары самой категории и всех её потомков.
+func buildCategoryTree() ([]dto.CategoryTreeNode, error) {
+	var cats []models.Category
+	if err := config.DB.Order(categoryOrder).Find(&cats).Error; err != nil {
+		return nil, err
+	}
+
+	type countRow struct {
+		CategoryID uint
+		Count      int64
+	}
+	var counts []countRow
+	if err := config.DB.Model(&models.Product{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("is_active = ?", true).
+		Group("category_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	own := make(map[uint]int64, len(counts))
+	for _, r := range counts {
+		own[r.CategoryID] = r.Count
+	}
+
+	children := make(map[uint][]models.Category)
+	roots := make([]models.Category, 0)
+	exists := make(map[uint]bool, len(cats))
+	for _, cat := range cats {
+		exists[cat.ID] = true
+	}
+	for _, cat := range cats {
+		// категория с удалённым родителем показывается в корне
+		if cat.ParentID == nil || !exists[*cat.ParentID] {
+			roots = append(roots, cat)
+			continue
+		}
+		children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+	}
+
+	visited := make(map[uint]bool, len(cats))
+	var build func(cat models.Category) dto.CategoryTreeNode
+	build = func(cat models.Category) dto.CategoryTreeNode {
+		visited[cat.ID] = true
+		node := dto.CategoryTreeNode{
+			ID:           cat.ID,
+			Name:         cat.Name,
+			Slug:         cat.Slug,
+			ImageURL:     cat.ImageURL,
+			ProductCount: own[cat.ID],
+			Children:     []dto.CategoryTreeNode{},
+		}
+		for _, ch := range children[cat.ID] {
+			if visited[ch.ID] {
+				continue
+			}
+			child := build(ch)
+			node.ProductCount += child.ProductCount
+			node.Children = append(node.Children, child)
+		}
+		return node
+	}
+
+	tree := make([]dto.CategoryTreeNode, 0, len(roots))
+	for _, r := range roots {
+		tree = append(tree, build(r))
+	}
+	return tree, nil
+}
+
+// categoryDescendantsSQL — id категории и всех её потомков. UNION (а не
+// UNION ALL) останавливает рекурсию, даже если в данных оказался цикл.
+const categoryDescendantsSQL = `WITH RECURSIVE sub AS (
+	SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+	UNION
+	SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+	WHERE c.deleted_at IS NULL
+) SELECT id FROM sub`
+
+// categoryBreadcrumbs — цепочка от корня до категории id включительно.
+func categoryBreadcrumbs(db *gorm.DB, id uint) ([]dto.CategoryRef, error) {
+	crumbs := make([]dto.CategoryRef, 0)
+	err := db.Raw(`WITH RECURSIVE chain AS (
+		SELECT id, name, slug, parent_id, 0 AS depth FROM categories
+		WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT c.id, c.name, c.slug, c.parent_id, chain.depth + 1 FROM categories c
+		JOIN chain ON c.id = chain.parent_id
+		WHERE c.deleted_at IS NULL AND chain.depth < 100
+	) SELECT id, name, slug FROM chain ORDER BY depth DESC`, id).Scan(&crumbs).Error
+	return crumbs, err
+}
+
+// GetCategoryTree — всё дерево категорий со счётчиками товаров.
+func GetCategoryTree(c *gin.Context) {
+	tree, err := cachedCategoryTree()
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, tree)
+}
@@ -0,0 +1,608 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkoutError — заказ нельзя оформить, список проблем по позициям.
+type checkoutError struct {
+	lines []dto.OrderLineError
+}
+
+func (e *checkoutError) Error() string {
+	return fmt.Sprintf("checkout rejected: %d line(s)", len(e.lines))
+}
+
+func Checkout(c *gin.Context) {
+	var req dto.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondError(c, http.StatusBadRequest, "cart is empty")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	userID := currentUserID(c)
+
+	var order models.Order
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// блокируем корзину до чтения позиций: повторное нажатие «Оформить»
+		// дождётся первого заказа и увидит уже пустую корзину
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&models.Cart{}, cart.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &checkoutError{}
+			}
+			return err
+		}
+
+		var items []models.CartItem
+		if err := tx.Where("cart_id = ?", cart.ID).Order("id asc").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return &checkoutError{}
+		}
+
+		ids := make([]uint, 0, len(items))
+		for _, it := range items {
+			ids = append(ids, it.ProductID)
+		}
+
+		// блокируем строки товаров до конца транзакции (в порядке id, чтобы не ловить дедлоки),
+		// так два покупателя не смогут одновременно забрать последнюю банку
+		var products []models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).
+			Order("id asc").
+			Find(&products).Error; err != nil {
+			return err
+		}
+		var tastes []models.ProductTaste
+		if err := tx.Where("product_id IN ?", ids).Find(&tastes).Error; err != nil {
+			return err
+		}
+
+		byID := make(map[uint]*models.Product, len(products))
+		for i := range products {
+			byID[products[i].ID] = &products[i]
+		}
+		for _, t := range tastes {
+			if p, ok := byID[t.ProductID]; ok {
+				p.Tastes = append(p.Tastes, t)
+			}
+		}
+
+		// у товара без вкусов остаток общий, у вариантов — свой;
+		// одинаковые позиции суммируются перед проверкой
+		type stockKey struct {
+			productID uint
+			variantID uint
+		}
+		keyOf := func(p models.Product, taste string) stockKey {
+			if v, ok := findProductTaste(p, taste); ok {
+				return stockKey{productID: p.ID, variantID: v.ID}
+			}
+			return stockKey{productID: p.ID}
+		}
+
+		need := make(map[stockKey]int, len(items))
+		for _, it := range items {
+			if p, ok := byID[it.ProductID]; ok {
+				need[keyOf(*p, it.Taste)] += it.Quantity
+			}
+		}
+
+		var lineErrors []dto.OrderLineError
+		for _, it := range items {
+			p, ok := byID[it.ProductID]
+			if !ok {
+				lineErrors = append(lineErrors, dto.OrderLineError{
+					ProductID: it.ProductID,
+					Taste:     it.Taste,
+					Requested: it.Quantity,
+					Error:     "товар недоступен",
+				})
+				continue
+			}
+
+			stock := lineStock(*p, it.Taste)
+			problem := checkCartLine(*p, it.Taste, it.Quantity)
+			if problem == "" && need[keyOf(*p, it.Taste)] > stock {
+				problem = fmt.Sprintf("в наличии только %d шт.", stock)
+			}
+			if problem != "" {
+				lineErrors = append(lineErrors, dto.OrderLineError{
+					ProductID: p.ID,
+					Name:      p.Name,
+					Taste:     it.Taste,
+					Requested: it.Quantity,
+					Available: stock,
+					Error:     problem,
+				})
+			}
+		}
+		if len(lineErrors) > 0 {
+			return &checkoutError{lines: lineErrors}
+		}
+
+		order = models.Order{
+			UserID:       userID,
+			Status:       models.OrderStatusNew,
+			CustomerName: strings.TrimSpace(req.CustomerName),
+			Phone:        strings.TrimSpace(req.Phone),
+			Address:      strings.TrimSpace(req.Address),
+			Comment:      strings.TrimSpace(req.Comment),
+		}
+
+		for _, it := range items {
+			p := byID[it.ProductID]
+			price := productUnitPrice(*p, it.Taste)
+			line := models.OrderItem{
+				ProductID:   p.ID,
+				VariantID:   variantIDOf(*p, it.Taste),
+				ProductName: p.Name,
+				ProductSlug: p.Slug,
+				Taste:       it.Taste,
+				Price:       price,
+				Quantity:    it.Quantity,
+				LineTotal:   price * int64(it.Quantity),
+			}
+			order.Subtotal += line.LineTotal
+			order.Items = append(order.Items, line)
+		}
+		order.Total = order.Subtotal
+
+		var promo models.PromoCode
+		if code := strings.TrimSpace(req.PromoCode); code != "" {
+			var err error
+			// блокировка нужна, чтобы лимит использований не превысили параллельные заказы
+			promo, err = findPromo(tx, code, true)
+			if err != nil {
+				return err
+			}
+			lines := make([]promoLine, 0, len(order.Items))
+			for _, line := range order.Items {
+				lines = append(lines, promoLine{
+					ProductID:  line.ProductID,
+					CategoryID: byID[line.ProductID].CategoryID,
+					Total:      line.LineTotal,
+				})
+			}
+			discount, err := evaluatePromo(tx, promo, lines, userID, time.Now())
+			if err != nil {
+				return err
+			}
+			order.PromoCode = promo.Code
+			order.Discount = discount
+			order.Total = order.Subtotal - discount
+		}
+
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		if promo.ID != 0 {
+			if err := redeemPromo(tx, promo, order.ID, userID, order.Discount); err != nil {
+				return err
+			}
+		}
+
+		first := models.OrderStatusHistory{
+			OrderID:   order.ID,
+			ToStatus:  models.OrderStatusNew,
+			ChangedBy: userID,
+		}
+		if err := tx.Create(&first).Error; err != nil {
+			return err
+		}
+		order.History = []models.OrderStatusHistory{first}
+
+		reference := fmt.Sprintf("order:%d", order.ID)
+		for key, qty := range need {
+			m := stockMove{
+				ProductID: key.productID,
+				Kind:      models.StockSale,
+				Delta:     -qty,
+				Reason:    "продажа",
+				ActorID:   userID,
+				Reference: reference,
+			}
+			if key.variantID != 0 {
+				vid := key.variantID
+				m.VariantID = &vid
+			}
+			if _, err := moveStock(tx, m); err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error
+	})
+
+	if err != nil {
+		var ce *checkoutError
+		if errors.As(err, &ce) {
+			if len(ce.lines) == 0 {
+				utils.RespondError(c, http.StatusBadRequest, "cart is empty")
+				return
+			}
+			utils.RespondError(c, http.StatusConflict, "some items cannot be ordered", gin.H{"lines": ce.lines})
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			utils.RespondError(c, http.StatusConflict, "not enough stock")
+			return
+		}
+		var pe *promoError
+		if errors.As(err, &pe) {
+			utils.RespondError(c, http.StatusUnprocessableEntity, pe.msg)
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, orderToResp(order))
+}
+
+func ListMyOrders(c *gin.Context) {
+	uid, ok := c.Get("userID")
+	if !ok {
+		utils.RespondError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.Order{}).Where("user_id = ?", uid.(uint))
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var orders []models.Order
+	if err := db.Preload("Items").
+		Order("created_at desc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Find(&orders).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.OrderResponse, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, orderToResp(o))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func GetMyOrder(c *gin.Context) {
+	uid, ok := c.Get("userID")
+	if !ok {
+		utils.RespondError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	orderID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("Items").
+		Preload("History", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id asc")
+		}).
+		Where("user_id = ?", uid.(uint)).
+		First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "order not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, orderToResp(order))
+}
+
+func AdminListOrders(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.Order{})
+
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status IN ?", strings.Split(status, ","))
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		db = db.Where("customer_name ILIKE ? OR phone ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	if uid := c.Query("user_id"); uid != "" {
+		if id, err := strconv.Atoi(uid); err == nil && id > 0 {
+			db = db.Where("user_id = ?", id)
+		}
+	}
+
+	if from := c.Query("date_from"); from != "" {
+		if t, err := time.Parse("2006-01-02", from); err == nil {
+			db = db.Where("created_at >= ?", t)
+		}
+	}
+	if to := c.Query("date_to"); to != "" {
+		if t, err := time.Parse("2006-01-02", to); err == nil {
+			db = db.Where("created_at < ?", t.AddDate(0, 0, 1))
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var orders []models.Order
+	if err := db.Preload("Items").
+		Order("created_at desc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Find(&orders).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.OrderResponse, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, orderToResp(o))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminGetOrder(c *gin.Context) {
+	orderID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("Items").
+		Preload("History", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id asc")
+		}).
+		First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "order not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, orderToResp(order))
+}
+
+// errIllegalTransition — переход между статусами не разрешён машиной состояний.
+var errIllegalTransition = errors.New("illegal order status transition")
+
+func AdminUpdateOrderStatus(c *gin.Context) {
+	orderID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.OrderStatusUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	next := models.OrderStatus(req.Status)
+	if !next.Valid() {
+		utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("unknown status '%s'", req.Status))
+		return
+	}
+
+	actorID := currentUserID(c)
+
+	var order models.Order
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, orderID).Error; err != nil {
+			return err
+		}
+
+		if !order.Status.CanTransitionTo(next) {
+			return errIllegalTransition
+		}
+
+		if next.RestocksOnEnter() {
+			if err := restockOrder(tx, order.ID, next, actorID); err != nil {
+				return err
+			}
+		}
+
+		if next == models.OrderStatusCancelled {
+			if err := releasePromo(tx, order.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&models.OrderStatusHistory{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   next,
+			ChangedBy:  actorID,
+			Comment:    strings.TrimSpace(req.Comment),
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&order).Update("status", next).Error
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "order not found")
+			return
+		}
+		if errors.Is(err, errIllegalTransition) {
+			allowed := make([]string, 0)
+			for _, st := range order.Status.NextStatuses() {
+				allowed = append(allowed, string(st))
+			}
+			utils.RespondError(c, http.StatusConflict,
+				fmt.Sprintf("cannot change status from '%s' to '%s'", order.Status, next),
+				gin.H{"allowed": allowed},
+			)
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	AdminGetOrder(c)
+}
+
+// restockOrder возвращает позиции заказа на склад. Товар мог быть удалён —
+// журнал всё равно получает запись, а остаток возвращается в (удалённый) товар.
+func restockOrder(tx *gorm.DB, orderID uint, status models.OrderStatus, actorID *uint) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+	reason := "отмена заказа"
+	if status == models.OrderStatusReturned {
+		reason = "возврат заказа"
+	}
+	for _, it := range items {
+		_, err := moveStock(tx, stockMove{
+			ProductID: it.ProductID,
+			VariantID: it.VariantID,
+			Kind:      models.StockReturn,
+			Delta:     it.Quantity,
+			Reason:    reason,
+			ActorID:   actorID,
+			Reference: fmt.Sprintf("order:%d", orderID),
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// товар или вариант удалён окончательно — возвращать некуда
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func variantIDOf(p models.Product, taste string) *uint {
+	if v, ok := findProductTaste(p, taste); ok {
+		return &v.ID
+	}
+	return nil
+}
+
+func orderToResp(o models.Order) dto.OrderResponse {
+	resp := dto.OrderResponse{
+		ID:           o.ID,
+		UserID:       o.UserID,
+		Status:       string(o.Status),
+		CustomerName: o.CustomerName,
+		Phone:        o.Phone,
+		Address:      o.Address,
+		Comment:      o.Comment,
+		Subtotal:     o.Subtotal,
+		PromoCode:    o.PromoCode,
+		Discount:     o.Discount,
+		Total:        o.Total,
+		Items:        make([]dto.OrderItemResponse, 0, len(o.Items)),
+		NextStatuses: []string{},
+		CreatedAt:    o.CreatedAt,
+	}
+	for _, st := range o.Status.NextStatuses() {
+		resp.NextStatuses = append(resp.NextStatuses, string(st))
+	}
+	for _, h := range o.History {
+		resp.History = append(resp.History, dto.OrderStatusChange{
+			FromStatus: string(h.FromStatus),
+			ToStatus:   string(h.ToStatus),
+			ChangedBy:  h.ChangedBy,
+			Comment:    h.Comment,
+			CreatedAt:  h.CreatedAt,
+		})
+	}
+	for _, it := range o.Items {
+		resp.Items = append(resp.Items, dto.OrderItemResponse{
+			ID:          it.ID,
+			ProductID:   it.ProductID,
+			ProductName: it.ProductName,
+			ProductSlug: it.ProductSlug,
+			Taste:       it.Taste,
+			Price:       it.Price,
+			Quantity:    it.Quantity,
+			LineTotal:   it.LineTotal,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"clen_shop/models"
+	"time"
+)
+
+// salePriceSQL — то же, что effectivePrice, в SQL.
+const salePriceSQL = `(CASE WHEN products.sale_price IS NOT NULL
+	AND (products.sale_starts_at IS NULL OR products.sale_starts_at <= now())
+	AND (products.sale_ends_at IS NULL OR products.sale_ends_at > now())
+	THEN products.sale_price ELSE products.price END)`
+
+// effectivePriceSQL — то же, что listPrice, но для фильтров и сортировки в SQL.
+const effectivePriceSQL = `COALESCE((SELECT MIN(COALESCE(pv.price, ` + salePriceSQL + `))
+	FROM product_tastes pv
+	WHERE pv.product_id = products.id AND pv.is_active AND pv.deleted_at IS NULL), ` + salePriceSQL + `)`
+
+func saleActive(p models.Product, now time.Time) bool {
+	if p.SalePrice == nil {
+		return false
+	}
+	if p.SaleStartsAt != nil && now.Before(*p.SaleStartsAt) {
+		return false
+	}
+	if p.SaleEndsAt != nil && !now.Before(*p.SaleEndsAt) {
+		return false
+	}
+	return true
+}
+
+// effectivePrice — цена товара в момент now с учётом запланированной распродажи.
+func effectivePrice(p models.Product, now time.Time) int64 {
+	if saleActive(p, now) {
+		return *p.SalePrice
+	}
+	return p.Price
+}
+
+// listPrice — цена товара в каталоге: наименьшая цена единицы среди активных
+// вариантов (см. productUnitPrice), а без вариантов — effectivePrice.
+// Фильтр и сортировка по цене считают её так же (effectivePriceSQL).
+func listPrice(p models.Product, now time.Time) int64 {
+	price := effectivePrice(p, now)
+	lowest, found := price, false
+	for _, v := range p.Tastes {
+		if !v.IsActive {
+			continue
+		}
+		unit := price
+		if v.Price != nil {
+			unit = *v.Price
+		}
+		if !found || unit < lowest {
+			lowest, found = unit, true
+		}
+	}
+	return lowest
+}
+
+// priceInfo возвращает текущую цену, зачёркнутую цену и процент скидки.
+// Зачёркнутая цена — CompareAtPrice, если она задана, иначе базовая Price
+// на время распродажи.
+func priceInfo(p models.Product, now time.Time) (effective, original int64, percent int) {
+	effective = listPrice(p, now)
+	original = effective
+	if saleActive(p, now) {
+		original = p.Price
+	}
+	if p.CompareAtPrice != nil && *p.CompareAtPrice > original {
+		original = *p.CompareAtPrice
+	}
+	if original <= effective {
+		return effective, effective, 0
+	}
+	percent = int((original - effective) * 100 / original)
+	return effective, original, percent
+}
+
+// productUnitPrice — актуальная цена единицы товара (с учётом вкуса):
+// собственная цена варианта или цена товара с учётом распродажи. Распродажа
+// с ценами вариантов не сочетается (см. checkVariantSale).
+func productUnitPrice(p models.Product, taste string) int64 {
+	if v, ok := findProductTaste(p, taste); ok && v.Price != nil {
+		return *v.Price
+	}
+	return effectivePrice(p, time.Now())
+}
+
+// checkSaleRules проверяет согласованность цен распродажи.
+func checkSaleRules(p models.Product) string {
+	if p.SalePrice != nil && (*p.SalePrice < 1 || *p.SalePrice >= p.Price) {
+		return "sale_price must be less than price"
+	}
+	if p.CompareAtPrice != nil && *p.CompareAtPrice < 1 {
+		return "compare_at_price must be positive"
+	}
+	if p.SaleStartsAt != nil && p.SaleEndsAt != nil && !p.SaleEndsAt.After(*p.SaleStartsAt) {
+		return "sale_ends_at must be after sale_starts_at"
+	}
+	return ""
+}
+
+// checkVariantSale — распродажа задаётся ценой товара и к вариантам со своей
+// ценой не применяется, поэтому вместе их не допускаем.
+func checkVariantSale(p models.Product, variants []models.ProductTaste) string {
+	if p.SalePrice == nil {
+		return ""
+	}
+	for _, v := range variants {
+		if v.Price != nil {
+			return "sale_price cannot be combined with variant prices"
+		}
+	}
+	return ""
+}
+
+// samePricing — совпадают ли базовая цена и распродажа; иначе правка попадает в историю цен.
+func samePricing(a, b models.Product) bool {
+	return a.Price == b.Price &&
+		samePrice(a.SalePrice, b.SalePrice) &&
+		sameTime(a.SaleStartsAt, b.SaleStartsAt) &&
+		sameTime(a.SaleEndsAt, b.SaleEndsAt)
+}
+
+func samePrice(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// saleStartedAt — момент, с которого действует текущая распродажа: последняя
+// правка, выставившая нынешнюю цену распродажи (правки одних дат не в счёт),
+// или начало распродажи, если оно позже.
+func saleStartedAt(p models.Product, changes []models.PriceChange) time.Time {
+	start := p.CreatedAt
+	for i := len(changes) - 1; i >= 0; i-- {
+		if !samePrice(changes[i].OldSalePrice, p.SalePrice) {
+			start = changes[i].CreatedAt
+			break
+		}
+	}
+	if p.SaleStartsAt != nil && p.SaleStartsAt.After(start) {
+		start = *p.SaleStartsAt
+	}
+	return start
+}
+
+// lowestEffectivePrice — минимальная эффективная цена товара p на отрезке
+// [from, to): цена распродажи, если её окно пересекается с отрезком, иначе базовая.
+func lowestEffectivePrice(p models.Product, from, to time.Time) int64 {
+	if p.SalePrice == nil || !from.Before(to) {
+		return p.Price
+	}
+	if p.SaleStartsAt != nil && !p.SaleStartsAt.Before(to) {
+		return p.Price
+	}
+	if p.SaleEndsAt != nil && !p.SaleEndsAt.After(from) {
+		return p.Price
+	}
+	return min(p.Price, *p.SalePrice)
+}
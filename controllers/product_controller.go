@@ -3,7 +3,9 @@ package controllers
 import (
 	"clen_shop/config"
 	"clen_shop/dto"
+	"clen_shop/images"
 	"clen_shop/models"
+	"clen_shop/search"
 	"clen_shop/utils"
 	"clen_shop/validators"
 	"errors"
@@ -11,10 +13,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateProduct(c *gin.Context) {
@@ -38,55 +42,124 @@ func CreateProduct(c *gin.Context) {
 		Slug:        req.Slug,
 		Description: req.Description,
 		Price:       req.Price,
-		Stock:       req.Stock,
 		IsActive:    true,
 		CategoryID:  req.CategoryID,
+
+		CompareAtPrice: req.CompareAtPrice,
+		SalePrice:      req.SalePrice,
+		SaleStartsAt:   req.SaleStartsAt,
+		SaleEndsAt:     req.SaleEndsAt,
 	}
 
 	if req.IsActive != nil {
 		p.IsActive = *req.IsActive
 	}
 
-	if err := config.DB.Create(&p).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "db error")
+	brand, err := loadBrand(config.DB, req.BrandID)
+	if err != nil {
+		respondBrandError(c, err)
 		return
 	}
+	p.BrandID, p.Brand = req.BrandID, brand
 
-	if len(req.Images) > 0 {
-		images := make([]models.ProductImage, 0, len(req.Images))
-		for _, img := range req.Images {
-			images = append(images, models.ProductImage{
-				ProductID: p.ID,
-				URL:       img.URL,
-				IsPrimary: img.IsPrimary,
-				SortOrder: img.SortOrder,
-			})
-		}
-		_ = config.DB.Create(&images).Error
+	if msg := checkSaleRules(p); msg != "" {
+		utils.RespondError(c, http.StatusBadRequest, msg)
+		return
 	}
 
-	if len(req.Tastes) > 0 {
-		tastes := make([]models.ProductTaste, 0, len(req.Tastes))
-		for _, t := range req.Tastes {
-			t = strings.TrimSpace(t)
-			if t == "" {
-				continue
+	variants := req.Variants
+	if len(variants) == 0 {
+		variants = tastesToVariantInputs(req.Tastes, nil)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+
+		imgs, err := applyImages(tx, p.ID, nil, req.Images)
+		if err != nil {
+			return err
+		}
+		p.Images = imgs
+
+		attrs, err := applyAttributes(tx, p.ID, p.CategoryID, req.Attributes, false)
+		if err != nil {
+			return err
+		}
+		p.Attributes = attrs
+
+		if len(variants) > 0 {
+			created, err := applyVariants(tx, p.ID, nil, variants, currentUserID(c))
+			if err != nil {
+				return err
+			}
+			if msg := checkVariantSale(p, created); msg != "" {
+				return &errVariantInput{msg: msg}
+			}
+			p.Tastes = created
+			if len(created) > 0 {
+				p.Stock = variantsStock(created)
+				if err := syncProductStock(tx, p.ID); err != nil {
+					return err
+				}
+				return search.RefreshProducts(tx, p.ID)
 			}
-			tastes = append(tastes, models.ProductTaste{
+		}
+
+		if req.Stock > 0 {
+			rec, err := moveStock(tx, stockMove{
 				ProductID: p.ID,
-				Name:      t,
+				Kind:      models.StockReceipt,
+				Delta:     req.Stock,
+				Reason:    "создание товара",
+				ActorID:   currentUserID(c),
 			})
-		}
-		if len(tastes) > 0 {
-			if err := config.DB.Create(&tastes).Error; err == nil {
-				p.Tastes = tastes
+			if err != nil {
+				return err
 			}
+			p.Stock = rec.Balance
+		}
+
+		return search.RefreshProducts(tx, p.ID)
+	})
+
+	if err != nil {
+		if ve, ok := isVariantInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ve.Error())
+			return
+		}
+		if ae, ok := isAttributeInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ae.Error())
+			return
+		}
+		if ie, ok := isImageInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ie.Error())
+			return
 		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug or sku already exists")
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			utils.RespondError(c, http.StatusConflict, "stock cannot become negative")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
 	}
 
+	invalidateCategoryTree()
 	utils.RespondCreated(c, productToResp(p))
 }
 
+// errProductInput — ошибка во входных данных товара, выявленная внутри транзакции.
+type errProductInput struct {
+	msg string
+}
+
+func (e *errProductInput) Error() string { return e.msg }
+
 func UpdateProduct(c *gin.Context) {
 	var req dto.ProductUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -103,18 +176,61 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var p models.Product
+	id, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
 
-	if err := config.DB.Preload("Images").First(&p, c.Param("id")).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.RespondError(c, http.StatusNotFound, "product not found")
+	// бренд от товара не зависит — проверяем его до транзакции
+	var brand *models.Brand
+	if req.BrandID.Set {
+		b, err := loadBrand(config.DB, req.BrandID.Value)
+		if err != nil {
+			respondBrandError(c, err)
 			return
 		}
-		utils.RespondError(c, http.StatusInternalServerError, "db error")
-		return
+		brand = b
 	}
 
+	var p models.Product
+
 	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// товар читаем под блокировкой: правки картинок (см. lockProduct)
+		// и одновременные правки товара идут по очереди
+		if err := lockProduct(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Preload("Images").Preload("Tastes", orderVariants).Preload("Brand").
+			Preload("Attributes.Attribute").
+			First(&p, id).Error; err != nil {
+			return err
+		}
+
+		old := p
+		if req.Price != nil {
+			p.Price = *req.Price
+		}
+		if req.CompareAtPrice.Set {
+			p.CompareAtPrice = req.CompareAtPrice.Value
+		}
+		if req.SalePrice.Set {
+			p.SalePrice = req.SalePrice.Value
+		}
+		if req.SaleStartsAt.Set {
+			p.SaleStartsAt = req.SaleStartsAt.Value
+		}
+		if req.SaleEndsAt.Set {
+			p.SaleEndsAt = req.SaleEndsAt.Value
+		}
+		if req.BrandID.Set {
+			p.BrandID, p.Brand = req.BrandID.Value, brand
+		}
+
+		if msg := checkSaleRules(p); msg != "" {
+			return &errProductInput{msg: msg}
+		}
+
 		if req.Name != nil {
 			p.Name = *req.Name
 		}
@@ -124,161 +240,241 @@ func UpdateProduct(c *gin.Context) {
 		if req.Description != nil {
 			p.Description = *req.Description
 		}
-		if req.Price != nil {
-			p.Price = *req.Price
-		}
-		if req.Stock != nil {
-			p.Stock = *req.Stock
-		}
 		if req.IsActive != nil {
 			p.IsActive = *req.IsActive
 		}
+		oldCategoryID := p.CategoryID
 		if req.CategoryID != nil {
 			p.CategoryID = *req.CategoryID
 		}
 
-		if err := tx.Save(&p).Error; err != nil {
+		// остаток меняется только через складской журнал
+		if err := tx.Omit("stock", clause.Associations).Save(&p).Error; err != nil {
 			return err
 		}
 
-		if req.Images != nil && len(req.Images) > 0 {
-			if err := tx.Where("product_id = ?", p.ID).Delete(&models.ProductImage{}).Error; err != nil {
+		// при смене категории прежние значения проверяются по атрибутам новой,
+		// чужие для неё отбрасываются
+		if req.Attributes != nil || p.CategoryID != oldCategoryID {
+			values, lenient := attributeValuesMap(p.Attributes), true
+			if req.Attributes != nil {
+				values, lenient = *req.Attributes, false
+			}
+			attrs, err := applyAttributes(tx, p.ID, p.CategoryID, values, lenient)
+			if err != nil {
 				return err
 			}
+			p.Attributes = attrs
+		}
 
-			images := make([]models.ProductImage, 0, len(req.Images))
-			for _, img := range req.Images {
-				images = append(images, models.ProductImage{
-					ProductID: p.ID,
-					URL:       img.URL,
-					IsPrimary: img.IsPrimary,
-					SortOrder: img.SortOrder,
-				})
-			}
-			if err := tx.Create(&images).Error; err != nil {
+		if !samePricing(old, p) {
+			if err := tx.Create(&models.PriceChange{
+				ProductID: p.ID,
+				OldPrice:  old.Price,
+				NewPrice:  p.Price,
+				ChangedBy: currentUserID(c),
+
+				OldSalePrice:    old.SalePrice,
+				OldSaleStartsAt: old.SaleStartsAt,
+				OldSaleEndsAt:   old.SaleEndsAt,
+				NewSalePrice:    p.SalePrice,
+				NewSaleStartsAt: p.SaleStartsAt,
+				NewSaleEndsAt:   p.SaleEndsAt,
+			}).Error; err != nil {
 				return err
 			}
-			p.Images = images
 		}
 
-		if req.Tastes != nil {
-			if err := tx.Where("product_id = ?", p.ID).Delete(&models.ProductTaste{}).Error; err != nil {
+		if req.Images != nil {
+			imgs, err := applyImages(tx, p.ID, p.Images, *req.Images)
+			if err != nil {
 				return err
 			}
+			p.Images = imgs
+		}
 
-			incoming := *req.Tastes
-			newTastes := make([]models.ProductTaste, 0, len(incoming))
-			for _, t := range incoming {
-				t = strings.TrimSpace(t)
-				if t == "" {
-					continue
-				}
-				newTastes = append(newTastes, models.ProductTaste{
-					ProductID: p.ID,
-					Name:      t,
-				})
+		var variants *[]dto.ProductVariantInput
+		if req.Variants != nil {
+			variants = req.Variants
+		} else if req.Tastes != nil {
+			converted := tastesToVariantInputs(*req.Tastes, p.Tastes)
+			variants = &converted
+		}
+
+		if variants != nil {
+			updated, err := applyVariants(tx, p.ID, p.Tastes, *variants, currentUserID(c))
+			if err != nil {
+				return err
 			}
+			p.Tastes = updated
+		}
+		if msg := checkVariantSale(p, p.Tastes); msg != "" {
+			return &errVariantInput{msg: msg}
+		}
 
-			if len(newTastes) > 0 {
-				if err := tx.Create(&newTastes).Error; err != nil {
-					return err
-				}
-				p.Tastes = newTastes
-			} else {
-				p.Tastes = []models.ProductTaste{}
+		if err := search.RefreshProducts(tx, p.ID); err != nil {
+			return err
+		}
+
+		if len(p.Tastes) > 0 {
+			p.Stock = variantsStock(p.Tastes)
+			return syncProductStock(tx, p.ID)
+		}
+
+		if req.Stock != nil && *req.Stock != p.Stock {
+			rec, err := moveStock(tx, stockMove{
+				ProductID: p.ID,
+				Kind:      models.StockAdjustment,
+				Target:    req.Stock,
+				Reason:    "правка товара",
+				ActorID:   currentUserID(c),
+			})
+			if err != nil {
+				return err
 			}
+			p.Stock = rec.Balance
 		}
 
 		return nil
 	})
 
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
+		var pe *errProductInput
+		if errors.As(err, &pe) {
+			utils.RespondError(c, http.StatusBadRequest, pe.Error())
+			return
+		}
+		if ve, ok := isVariantInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ve.Error())
+			return
+		}
+		if ae, ok := isAttributeInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ae.Error())
+			return
+		}
+		if ie, ok := isImageInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ie.Error())
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "slug or sku already exists")
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			utils.RespondError(c, http.StatusConflict, "stock cannot become negative")
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "db error")
 		return
 	}
 
+	invalidateCategoryTree()
 	utils.RespondOK(c, productToResp(p))
 
 }
 
+// DeleteProduct переносит товар в корзину (/admin/trash), откуда его можно
+// восстановить или удалить окончательно.
 func DeleteProduct(c *gin.Context) {
-	if err := config.DB.Unscoped().Delete(&models.Product{}, c.Param("id")).Error; err != nil {
+	id, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var p models.Product
+		if err := tx.Select("id").First(&p, id).Error; err != nil {
+			return err
+		}
+		// раньше строки корзин покупателей удалялись каскадом, теперь — явно
+		if err := tx.Unscoped().Where("product_id = ?", p.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&p).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
 		utils.RespondError(c, http.StatusInternalServerError, "db error")
 		return
 	}
+	invalidateCategoryTree()
 	utils.RespondOK(c, gin.H{"deleted": true})
 }
 
 func ListProducts(c *gin.Context) {
+	f, err := parseProductFilter(c)
+	if err != nil {
+		respondFilterError(c, err)
+		return
+	}
+
+	resp, err := productListing(c, f)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, resp)
+}
+
+// productListing — страница витрины по фильтрам f: товары, total и фасеты.
+// Общая для каталога, страниц брендов и категорий.
+func productListing(c *gin.Context, f productFilter) (dto.ProductPage, error) {
 	page, limit := utils.GetPage(c)
-	q := c.Query("q")
-	categoryID := c.Query("category_id")
-	categorySlug := c.Query("category_slug")
-	priceMin := c.Query("price_min")
-	priceMax := c.Query("price_max")
 
 	allowedSort := map[string]string{
 		"name":       "name",
-		"price":      "price",
+		"price":      effectivePriceSQL,
 		"created_at": "created_at",
-	}
-
-	order := utils.BuildOrder(c.Query("sort"), allowedSort)
-
-	db := config.DB.Model(&models.Product{}).Where("is_active = ?", true)
+		"rating":     "rating_avg",
 
-	if q != "" {
-		db = db.Where("name ILIKE ? OR slug ILIKE ?", "%"+q+"%", "%"+q+"%")
+		"price_per_serving": pricePerServingSQL,
 	}
 
-	if categorySlug != "" {
-		var cat models.Category
-		if err := config.DB.Where("slug = ?", categorySlug).First(&cat).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				utils.RespondError(c, http.StatusNotFound, "category not found")
-				return
-			}
-			utils.RespondError(c, http.StatusInternalServerError, "db error")
-			return
-		}
+	sort := c.Query("sort")
+	attributeSorts(sort, allowedSort)
+	// при поиске по умолчанию — по релевантности
+	byRelevance := f.Q != "" && (sort == "" || sort == "relevance")
 
-		db = db.Where("category_id = ?", cat.ID)
+	// нечёткий поиск включается, только если точный ничего не нашёл
+	// среди отфильтрованных товаров
+	if err := f.resolveSearch(); err != nil {
+		return dto.ProductPage{}, err
 	}
 
-	if categoryID != "" {
-		if cid, err := strconv.Atoi(categoryID); err == nil {
-			db = db.Where("category_id = ?", cid)
-		}
-	}
+	db := f.scope(config.DB.Model(&models.Product{}), "")
 
-	if priceMin != "" {
-		if v, err := strconv.ParseInt(priceMin, 10, 64); err == nil {
-			db = db.Where("price >= ?", v)
-		}
-	}
-	if priceMax != "" {
-		if v, err := strconv.ParseInt(priceMax, 10, 64); err == nil {
-			db = db.Where("price <= ?", v)
-		}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return dto.ProductPage{}, err
 	}
 
-	var total int64
-	_ = db.Count(&total)
+	if byRelevance {
+		db = search.OrderByRank(db, f.Q, f.fuzzy)
+	} else {
+		db = db.Order(utils.BuildOrder(sort, allowedSort))
+	}
 
 	var items []models.Product
 
 	if err := db.
-		Preload("Images", func(tx *gorm.DB) *gorm.DB {
-			return tx.Order("is_primary desc, sort_order asc")
-		}).
-		Preload("Tastes"). // --- TASTES ---
-		Order(order).
+		Preload("Images", orderImages).
+		Preload("Images.Variants").
+		Preload("Tastes", orderVariants).
+		Preload("Brand").
+		Preload("Attributes", orderAttributes).
+		Preload("Attributes.Attribute").
 		Limit(limit).
 		Offset(utils.Offset(page, limit)).
 		Find(&items).Error; err != nil {
-
-		utils.RespondError(c, http.StatusInternalServerError, "db error")
-		return
+		return dto.ProductPage{}, err
 	}
 
 	resp := make([]dto.ProductResponse, 0, len(items))
@@ -286,22 +482,30 @@ func ListProducts(c *gin.Context) {
 		resp = append(resp, productWithImagesToResp(it))
 	}
 
-	utils.RespondOK(c, gin.H{
-		"page":  page,
-		"limit": limit,
-		"total": total,
-		"items": resp,
-	})
+	facets, err := productFacets(f)
+	if err != nil {
+		return dto.ProductPage{}, err
+	}
+
+	return dto.ProductPage{
+		Page:   page,
+		Limit:  limit,
+		Total:  total,
+		Items:  resp,
+		Facets: facets,
+	}, nil
 }
 
 func GetProduct(c *gin.Context) {
 	slug := c.Param("slug")
 	var p models.Product
 	if err := config.DB.
-		Preload("Images", func(tx *gorm.DB) *gorm.DB {
-			return tx.Order("is_primary desc, sort_order asc")
-		}).
-		Preload("Tastes"). // --- TASTES ---
+		Preload("Images", orderImages).
+		Preload("Images.Variants").
+		Preload("Tastes", orderVariants).
+		Preload("Brand").
+		Preload("Attributes", orderAttributes).
+		Preload("Attributes.Attribute").
 		Where("slug = ?", slug).
 		First(&p).Error; err != nil {
 
@@ -312,7 +516,113 @@ func GetProduct(c *gin.Context) {
 		utils.RespondError(c, http.StatusInternalServerError, "db error")
 		return
 	}
-	utils.RespondOK(c, productWithImagesToResp(p))
+	resp := productWithImagesToResp(p)
+	if lowest, err := lowestPriceBeforeSale(config.DB, p, time.Now()); err == nil {
+		resp.LowestPrice30d = &lowest
+	}
+	if crumbs, err := categoryBreadcrumbs(config.DB, p.CategoryID); err == nil {
+		resp.Breadcrumbs = crumbs
+	}
+	utils.RespondOK(c, resp)
+}
+
+// lowestPriceBeforeSale — минимальная эффективная цена товара (с учётом
+// распродаж) за 30 дней до начала текущей распродажи, а без распродажи — за
+// последние 30 дней. Окно кончается там, где распродажа началась, иначе минимум
+// всегда совпадал бы с ценой распродажи. Правки из истории цен делят окно на
+// отрезки: до каждой правки действовало её «старое» состояние, после
+// последней — текущее состояние товара.
+func lowestPriceBeforeSale(db *gorm.DB, p models.Product, now time.Time) (int64, error) {
+	var changes []models.PriceChange
+	if err := db.Where("product_id = ?", p.ID).
+		Order("created_at asc, id asc").
+		Find(&changes).Error; err != nil {
+		return 0, err
+	}
+
+	until := now
+	if saleActive(p, now) {
+		until = saleStartedAt(p, changes)
+	}
+	from := until.AddDate(0, 0, -30)
+	if p.CreatedAt.After(from) {
+		from = p.CreatedAt
+	}
+
+	var lowest int64
+	found := false
+	segment := func(state models.Product, to time.Time) {
+		if to.After(until) {
+			to = until
+		}
+		if !from.Before(to) {
+			return
+		}
+		if price := lowestEffectivePrice(state, from, to); !found || price < lowest {
+			lowest, found = price, true
+		}
+		from = to
+	}
+	for _, ch := range changes {
+		segment(models.Product{
+			Price:        ch.OldPrice,
+			SalePrice:    ch.OldSalePrice,
+			SaleStartsAt: ch.OldSaleStartsAt,
+			SaleEndsAt:   ch.OldSaleEndsAt,
+		}, ch.CreatedAt)
+	}
+	segment(p, until)
+
+	// до распродажи товар не продавался — другой цены, кроме текущей, не было
+	if !found {
+		return effectivePrice(p, now), nil
+	}
+	return lowest, nil
+}
+
+func AdminPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.PriceChange{}).Where("product_id = ?", id)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var items []models.PriceChange
+	if err := db.Order("id desc").Limit(limit).Offset(utils.Offset(page, limit)).Find(&items).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.PriceChangeResponse, 0, len(items))
+	for _, it := range items {
+		resp = append(resp, dto.PriceChangeResponse{
+			ID:        it.ID,
+			OldPrice:  it.OldPrice,
+			NewPrice:  it.NewPrice,
+			ChangedBy: it.ChangedBy,
+			CreatedAt: it.CreatedAt,
+
+			NewSalePrice:    it.NewSalePrice,
+			NewSaleStartsAt: it.NewSaleStartsAt,
+			NewSaleEndsAt:   it.NewSaleEndsAt,
+		})
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
 }
 
 func AdminGetProduct(c *gin.Context) {
@@ -325,10 +635,12 @@ func AdminGetProduct(c *gin.Context) {
 
 	var p models.Product
 	if err := config.DB.
-		Preload("Images", func(tx *gorm.DB) *gorm.DB {
-			return tx.Order("is_primary desc, sort_order asc")
-		}).
-		Preload("Tastes"). // --- TASTES ---
+		Preload("Images", orderImages).
+		Preload("Images.Variants").
+		Preload("Tastes", orderVariants).
+		Preload("Brand").
+		Preload("Attributes", orderAttributes).
+		Preload("Attributes.Attribute").
 		First(&p, id).Error; err != nil {
 
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -346,10 +658,12 @@ func AdminListProducts(c *gin.Context) {
 	page, limit := utils.GetPage(c)
 
 	db := config.DB.Model(&models.Product{}).
-		Preload("Images", func(tx *gorm.DB) *gorm.DB {
-			return tx.Order("is_primary desc, sort_order asc")
-		}).
-		Preload("Tastes") // --- TASTES ---
+		Preload("Images", orderImages).
+		Preload("Images.Variants").
+		Preload("Tastes", orderVariants).
+		Preload("Brand").
+		Preload("Attributes", orderAttributes).
+		Preload("Attributes.Attribute")
 
 	if q := c.Query("q"); q != "" {
 		db = db.Where("name ILIKE ? OR slug ILIKE ?", "%"+q+"%", "%"+q+"%")
@@ -394,6 +708,10 @@ func AdminListProducts(c *gin.Context) {
 	})
 }
 
+func orderVariants(tx *gorm.DB) *gorm.DB {
+	return tx.Order("sort_order asc, id asc")
+}
+
 func productToResp(p models.Product) dto.ProductResponse {
 	resp := dto.ProductResponse{
 		ID:          p.ID,
@@ -402,18 +720,42 @@ func productToResp(p models.Product) dto.ProductResponse {
 		Description: p.Description,
 		Price:       p.Price,
 		Stock:       p.Stock,
+
+		CompareAtPrice: p.CompareAtPrice,
+		SalePrice:      p.SalePrice,
+		SaleStartsAt:   p.SaleStartsAt,
+		SaleEndsAt:     p.SaleEndsAt,
+
+		Rating:      roundRating(p.RatingAvg),
+		RatingCount: p.RatingCount,
 		IsActive:    p.IsActive,
 		CategoryID:  p.CategoryID,
+		BrandID:     p.BrandID,
 		Images:      []dto.ProductImageDTO{},
-		Tastes:      []string{}, // --- TASTES ---
+		Tastes:      []string{},
+		Variants:    []dto.ProductVariantDTO{},
 	}
 
-	// --- TASTES ---
-	if len(p.Tastes) > 0 {
-		resp.Tastes = make([]string, 0, len(p.Tastes))
-		for _, t := range p.Tastes {
+	resp.EffectivePrice, resp.OriginalPrice, resp.DiscountPercent = priceInfo(p, time.Now())
+
+	resp.Attributes = attributesToDTO(p.Attributes)
+	resp.PricePerServing = pricePerServing(p.Attributes, resp.EffectivePrice)
+
+	if p.Brand != nil {
+		resp.Brand = &dto.BrandRef{
+			ID:      p.Brand.ID,
+			Name:    p.Brand.Name,
+			Slug:    p.Brand.Slug,
+			LogoURL: p.Brand.LogoURL,
+		}
+	}
+
+	// tastes — названия доступных вкусов, как и раньше; variants — полные данные
+	for _, t := range p.Tastes {
+		if t.IsActive {
 			resp.Tastes = append(resp.Tastes, t.Name)
 		}
+		resp.Variants = append(resp.Variants, variantToDTO(p, t))
 	}
 
 	return resp
@@ -424,13 +766,52 @@ func productWithImagesToResp(p models.Product) dto.ProductResponse {
 	if len(p.Images) > 0 {
 		resp.Images = make([]dto.ProductImageDTO, 0, len(p.Images))
 		for _, img := range p.Images {
-			resp.Images = append(resp.Images, dto.ProductImageDTO{
-				ID:        img.ID,
-				URL:       img.URL,
-				IsPrimary: img.IsPrimary,
-				SortOrder: img.SortOrder,
-			})
+			resp.Images = append(resp.Images, productImageToDTO(img))
+		}
+	}
+	return resp
+}
+
+func productImageToDTO(img models.ProductImage) dto.ProductImageDTO {
+	resp := dto.ProductImageDTO{
+		ID:        img.ID,
+		URL:       img.URL,
+		IsPrimary: img.IsPrimary,
+		SortOrder: img.SortOrder,
+		Variants:  map[string]dto.ImageVariantDTO{},
+	}
+
+	for _, v := range img.Variants {
+		iv := resp.Variants[v.Size]
+		if v.Format == models.ImageFormatWebP {
+			iv.WebP = v.URL
+		} else {
+			iv.URL = v.URL
 		}
+		iv.Width, iv.Height = v.Width, v.Height
+		resp.Variants[v.Size] = iv
 	}
+
+	// маленький исходник даёт одинаковую ширину у нескольких размеров — дубли в srcset не нужны
+	var srcset, webp []string
+	lastWidth := 0
+	for _, s := range images.Sizes {
+		iv, ok := resp.Variants[s.Name]
+		if !ok || iv.URL == "" || iv.Width == lastWidth {
+			continue
+		}
+		lastWidth = iv.Width
+		srcset = append(srcset, fmt.Sprintf("%s %dw", iv.URL, iv.Width))
+		if iv.WebP != "" {
+			webp = append(webp, fmt.Sprintf("%s %dw", iv.WebP, iv.Width))
+		}
+	}
+	resp.Srcset = strings.Join(srcset, ", ")
+	// WebP хранится, только если он меньше JPEG/PNG; неполный набор не отдаём,
+	// иначе браузер выбрал бы из него и не получил бы нужную ширину
+	if len(webp) == len(srcset) {
+		resp.SrcsetWebP = strings.Join(webp, ", ")
+	}
+
 	return resp
 }
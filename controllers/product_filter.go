@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/search"
+	"clen_shop/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// названия фасетов: фильтр фасета не применяется при подсчёте его же значений,
+// иначе после выбора одного вкуса остальные показывались бы с нулём
+const (
+	facetCategory = "category"
+	facetBrand    = "brand"
+	facetTaste    = "taste"
+	facetStock    = "in_stock"
+	facetPrice    = "price"
+)
+
+// priceBuckets — диапазоны цен для фасета; Max == 0 — без верхней границы.
+var priceBuckets = []priceRange{
+	{Min: 0, Max: 5000},
+	{Min: 5000, Max: 10000},
+	{Min: 10000, Max: 20000},
+	{Min: 20000, Max: 40000},
+	{Min: 40000},
+}
+
+type priceRange struct {
+	Min int64
+	Max int64
+}
+
+func (r priceRange) key() string {
+	if r.Max == 0 {
+		return fmt.Sprintf("%d-", r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+func (r priceRange) sql() (string, []interface{}) {
+	if r.Max == 0 {
+		return effectivePriceSQL + " >= ?", []interface{}{r.Min}
+	}
+	return "(" + effectivePriceSQL + " >= ? AND " + effectivePriceSQL + " < ?)", []interface{}{r.Min, r.Max}
+}
+
+// parsePriceRange разбирает «5000-10000» или «40000-».
+func parsePriceRange(s string) (priceRange, bool) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return priceRange{}, false
+	}
+	var r priceRange
+	var err error
+	if r.Min, err = strconv.ParseInt(from, 10, 64); err != nil || r.Min < 0 {
+		return priceRange{}, false
+	}
+	if to != "" {
+		if r.Max, err = strconv.ParseInt(to, 10, 64); err != nil || r.Max <= r.Min {
+			return priceRange{}, false
+		}
+	}
+	return r, true
+}
+
+var (
+	errFilterCategoryNotFound = errors.New("category not found")
+	errFilterBrandNotFound    = errors.New("brand not found")
+)
+
+func respondFilterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errFilterCategoryNotFound), errors.Is(err, errFilterBrandNotFound):
+		utils.RespondError(c, http.StatusNotFound, err.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+	}
+}
+
+// productFilter — фильтры витрины, общие для списка товаров и фасетов.
+type productFilter struct {
+	Q           string
+	fuzzy       bool // точный поиск ничего не нашёл, ищем нечётко
+	CategoryID  *uint
+	BrandIDs    []uint
+	Tastes      []string // в нижнем регистре
+	InStock     bool
+	PriceMin    *int64
+	PriceMax    *int64
+	PriceRanges []priceRange
+	Attrs       []attrFilter
+}
+
+// attrFilter — фильтр по атрибуту: attr.<code>=a,b (одно из значений)
+// или attr.<code>.min / attr.<code>.max для чисел.
+type attrFilter struct {
+	Code   string
+	Values []string // в нижнем регистре
+	Min    *float64
+	Max    *float64
+}
+
+// parseProductFilter читает фильтры из query. Некорректные значения
+// игнорируются, как и раньше; неизвестный category_slug — ошибка.
+func parseProductFilter(c *gin.Context) (productFilter, error) {
+	f := productFilter{Q: strings.TrimSpace(c.Query("q"))}
+
+	if slug := c.Query("category_slug"); slug != "" {
+		var cat models.Category
+		if err := config.DB.Select("id").Where("slug = ?", slug).First(&cat).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return f, errFilterCategoryNotFound
+			}
+			return f, err
+		}
+		f.CategoryID = &cat.ID
+	}
+	if v := c.Query("category_id"); v != "" {
+		if cid, err := strconv.Atoi(v); err == nil && cid > 0 {
+			id := uint(cid)
+			f.CategoryID = &id
+		}
+	}
+
+	// brand_slug=a,b — товары любого из брендов
+	if slugs := splitList(c.Query("brand_slug")); len(slugs) > 0 {
+		var ids []uint
+		if err := config.DB.Model(&models.Brand{}).
+			Where("slug IN ? AND is_active = ?", slugs, true).
+			Pluck("id", &ids).Error; err != nil {
+			return f, err
+		}
+		if len(ids) == 0 {
+			return f, errFilterBrandNotFound
+		}
+		f.BrandIDs = ids
+	}
+
+	for _, t := range strings.Split(c.Query("taste"), ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			f.Tastes = append(f.Tastes, t)
+		}
+	}
+
+	f.InStock = c.Query("in_stock") == "true"
+
+	if v, err := strconv.ParseInt(c.Query("price_min"), 10, 64); err == nil {
+		f.PriceMin = &v
+	}
+	if v, err := strconv.ParseInt(c.Query("price_max"), 10, 64); err == nil {
+		f.PriceMax = &v
+	}
+	for _, s := range strings.Split(c.Query("price_range"), ",") {
+		if r, ok := parsePriceRange(s); ok {
+			f.PriceRanges = append(f.PriceRanges, r)
+		}
+	}
+
+	f.Attrs = parseAttrFilters(c)
+
+	return f, nil
+}
+
+func parseAttrFilters(c *gin.Context) []attrFilter {
+	byCode := make(map[string]*attrFilter)
+	codes := make([]string, 0)
+	for key, vals := range c.Request.URL.Query() {
+		rest, ok := strings.CutPrefix(key, "attr.")
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		code, bound, _ := strings.Cut(rest, ".")
+		if !attrCodeRe.MatchString(code) {
+			continue
+		}
+		af, ok := byCode[code]
+		if !ok {
+			af = &attrFilter{Code: code}
+			byCode[code] = af
+			codes = append(codes, code)
+		}
+		switch bound {
+		case "":
+			for _, v := range splitList(vals[0]) {
+				af.Values = append(af.Values, strings.ToLower(v))
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(vals[0], 64)
+			if err != nil {
+				continue
+			}
+			if bound == "min" {
+				af.Min = &n
+			} else {
+				af.Max = &n
+			}
+		}
+	}
+
+	// порядок из map случаен — сортируем, чтобы SQL был стабильным
+	sort.Strings(codes)
+	out := make([]attrFilter, 0, len(codes))
+	for _, code := range codes {
+		af := byCode[code]
+		if len(af.Values) > 0 || af.Min != nil || af.Max != nil {
+			out = append(out, *af)
+		}
+	}
+	return out
+}
+
+// resolveSearch решает, точным или нечётким будет поиск по уже
+// отфильтрованным товарам; фасеты потом считаются в том же режиме.
+func (f *productFilter) resolveSearch() error {
+	if f.Q == "" {
+		return nil
+	}
+	q := f.Q
+	f.Q = ""
+	_, fuzzy, err := search.Apply(f.scope(config.DB.Model(&models.Product{}), ""), q)
+	f.Q, f.fuzzy = q, fuzzy
+	return err
+}
+
+// scope применяет все фильтры, кроме фильтра фасета skip.
+func (f productFilter) scope(db *gorm.DB, skip string) *gorm.DB {
+	db = db.Where("products.is_active = ?", true)
+
+	if f.Q != "" {
+		if f.fuzzy {
+			db = search.Fuzzy(db, f.Q)
+		} else {
+			db = search.Match(db, f.Q)
+		}
+	}
+
+	if f.CategoryID != nil && skip != facetCategory {
+		// категория вместе со всеми подкатегориями
+		db = db.Where("products.category_id IN ("+categoryDescendantsSQL+")", *f.CategoryID)
+	}
+
+	if len(f.BrandIDs) > 0 && skip != facetBrand {
+		db = db.Where("products.brand_id IN ?", f.BrandIDs)
+	}
+
+	if len(f.Tastes) > 0 && skip != facetTaste {
+		// при in_stock нужен именно выбранный вкус в наличии
+		cond := `EXISTS (SELECT 1 FROM product_tastes ft WHERE ft.product_id = products.id
+			AND ft.deleted_at IS NULL AND ft.is_active AND lower(ft.name) IN ?`
+		if f.InStock && skip != facetStock {
+			cond += ` AND ft.stock > 0`
+		}
+		db = db.Where(cond+")", f.Tastes)
+	}
+
+	if f.InStock && skip != facetStock {
+		db = db.Where("products.stock > 0")
+	}
+
+	for _, af := range f.Attrs {
+		cond := `EXISTS (SELECT 1 FROM product_attribute_values fav
+			JOIN attributes fa ON fa.id = fav.attribute_id AND fa.deleted_at IS NULL
+			WHERE fav.product_id = products.id AND fa.code = ?`
+		vars := []interface{}{af.Code}
+		if len(af.Values) > 0 {
+			cond += " AND lower(fav.text_value) IN ?"
+			vars = append(vars, af.Values)
+		}
+		if af.Min != nil {
+			cond += " AND fav.number_value >= ?"
+			vars = append(vars, *af.Min)
+		}
+		if af.Max != nil {
+			cond += " AND fav.number_value <= ?"
+			vars = append(vars, *af.Max)
+		}
+		db = db.Where(cond+")", vars...)
+	}
+
+	if skip != facetPrice {
+		if f.PriceMin != nil {
+			db = db.Where(effectivePriceSQL+" >= ?", *f.PriceMin)
+		}
+		if f.PriceMax != nil {
+			db = db.Where(effectivePriceSQL+" <= ?", *f.PriceMax)
+		}
+		if len(f.PriceRanges) > 0 {
+			conds := make([]string, 0, len(f.PriceRanges))
+			vars := make([]interface{}, 0)
+			for _, r := range f.PriceRanges {
+				sql, args := r.sql()
+				conds = append(conds, sql)
+				vars = append(vars, args...)
+			}
+			db = db.Where("("+strings.Join(conds, " OR ")+")", vars...)
+		}
+	}
+
+	return db
+}
+
+// productFacets считает значения фасетов для текущих фильтров.
+func productFacets(f productFilter) (dto.ProductFacets, error) {
+	facets := dto.ProductFacets{
+		Categories: []dto.FacetValue{},
+		Brands:     []dto.FacetValue{},
+		Tastes:     []dto.FacetValue{},
+		Prices:     []dto.PriceBucket{},
+	}
+	products := func(skip string) *gorm.DB {
+		return f.scope(config.DB.Model(&models.Product{}), skip)
+	}
+
+	if err := products(facetCategory).
+		Select("categories.id AS id, categories.slug AS value, categories.name AS label, COUNT(*) AS count").
+		Joins("JOIN categories ON categories.id = products.category_id AND categories.deleted_at IS NULL").
+		Group("categories.id, categories.slug, categories.name").
+		Order("count DESC, label ASC").
+		Scan(&facets.Categories).Error; err != nil {
+		return facets, err
+	}
+
+	if err := products(facetBrand).
+		Select("brands.id AS id, brands.slug AS value, brands.name AS label, COUNT(*) AS count").
+		Joins("JOIN brands ON brands.id = products.brand_id AND brands.deleted_at IS NULL AND brands.is_active").
+		Group("brands.id, brands.slug, brands.name").
+		Order("count DESC, label ASC").
+		Scan(&facets.Brands).Error; err != nil {
+		return facets, err
+	}
+
+	tastes := "JOIN product_tastes ON product_tastes.product_id = products.id AND product_tastes.deleted_at IS NULL AND product_tastes.is_active"
+	if f.InStock {
+		tastes += " AND product_tastes.stock > 0"
+	}
+	if err := products(facetTaste).
+		Select("lower(product_tastes.name) AS value, MIN(product_tastes.name) AS label, COUNT(DISTINCT products.id) AS count").
+		Joins(tastes).
+		Group("lower(product_tastes.name)").
+		Order("count DESC, label ASC").
+		Scan(&facets.Tastes).Error; err != nil {
+		return facets, err
+	}
+
+	if err := products(facetStock).
+		Where("products.stock > 0").
+		Count(&facets.InStock).Error; err != nil {
+		return facets, err
+	}
+
+	// все диапазоны и границы цен — одним запросом через FILTER
+	cols := []string{
+		"MIN(" + effectivePriceSQL + ") AS min_price",
+		"MAX(" + effectivePriceSQL + ") AS max_price",
+	}
+	vars := make([]interface{}, 0)
+	for i, b := range priceBuckets {
+		sql, args := b.sql()
+		cols = append(cols, fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS b%d", sql, i))
+		vars = append(vars, args...)
+	}
+	row := map[string]interface{}{}
+	if err := products(facetPrice).
+		Select(strings.Join(cols, ", "), vars...).
+		Scan(&row).Error; err != nil {
+		return facets, err
+	}
+	facets.PriceMin = toInt64(row["min_price"])
+	facets.PriceMax = toInt64(row["max_price"])
+	for i, b := range priceBuckets {
+		bucket := dto.PriceBucket{Value: b.key(), Min: b.Min, Count: toInt64(row[fmt.Sprintf("b%d", i)])}
+		if b.Max > 0 {
+			max := b.Max
+			bucket.Max = &max
+		}
+		facets.Prices = append(facets.Prices, bucket)
+	}
+
+	return facets, nil
+}
+
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/images"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errImageInput — ошибка во входных данных картинок (чужой id, неполный порядок).
+type errImageInput struct {
+	msg string
+}
+
+func (e *errImageInput) Error() string { return e.msg }
+
+func isImageInputErr(err error) (*errImageInput, bool) {
+	var ie *errImageInput
+	if errors.As(err, &ie) {
+		return ie, true
+	}
+	return nil, false
+}
+
+var errImageNotFound = errors.New("image not found")
+
+func orderImages(tx *gorm.DB) *gorm.DB {
+	return tx.Order("is_primary desc, sort_order asc")
+}
+
+// applyImages приводит картинки товара к списку inputs: совпавшие (по id,
+// затем по url) обновляются на месте и сохраняют ID, новые создаются, лишние
+// удаляются. Пустой список удаляет все картинки.
+func applyImages(tx *gorm.DB, productID uint, existing []models.ProductImage, inputs []dto.ProductImageInput) ([]models.ProductImage, error) {
+	byID := make(map[uint]models.ProductImage, len(existing))
+	for _, img := range existing {
+		byID[img.ID] = img
+	}
+
+	kept := make(map[uint]bool, len(inputs))
+	var primaryID *uint
+
+	for _, in := range inputs {
+		var img models.ProductImage
+		found := false
+		if in.ID != nil {
+			img, found = byID[*in.ID]
+			if !found {
+				return nil, &errImageInput{msg: fmt.Sprintf("image %d does not belong to product", *in.ID)}
+			}
+			if kept[img.ID] {
+				return nil, &errImageInput{msg: fmt.Sprintf("duplicate image %d", img.ID)}
+			}
+		} else {
+			for _, ex := range existing {
+				if !kept[ex.ID] && ex.URL == in.URL {
+					img, found = ex, true
+					break
+				}
+			}
+		}
+		if !found {
+			img = models.ProductImage{ProductID: productID}
+		}
+
+		img.URL = in.URL
+		img.SortOrder = in.SortOrder
+
+		// основную картинку выставляет normalizeImages после всех правок
+		if err := tx.Omit(clause.Associations, "is_primary").Save(&img).Error; err != nil {
+			return nil, err
+		}
+		if !found {
+			images.Enqueue(img.URL)
+		}
+
+		kept[img.ID] = true
+		if in.IsPrimary && primaryID == nil {
+			id := img.ID
+			primaryID = &id
+		}
+	}
+
+	for _, ex := range existing {
+		if kept[ex.ID] {
+			continue
+		}
+		if err := tx.Unscoped().Delete(&models.ProductImage{}, ex.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return normalizeImages(tx, productID, primaryID)
+}
+
+// normalizeImages нумерует картинки товара подряд и оставляет ровно одну
+// основную: primaryID, если задан, иначе текущую основную, иначе первую.
+func normalizeImages(tx *gorm.DB, productID uint, primaryID *uint) ([]models.ProductImage, error) {
+	var list []models.ProductImage
+	if err := tx.Where("product_id = ?", productID).
+		Order("sort_order asc, id asc").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	target := list[0].ID
+	if primaryID != nil {
+		target = *primaryID
+	} else {
+		for _, img := range list {
+			if img.IsPrimary {
+				target = img.ID
+				break
+			}
+		}
+	}
+
+	for i := range list {
+		if list[i].SortOrder != i {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", list[i].ID).
+				UpdateColumn("sort_order", i).Error; err != nil {
+				return nil, err
+			}
+			list[i].SortOrder = i
+		}
+		list[i].IsPrimary = list[i].ID == target
+	}
+
+	if err := tx.Model(&models.ProductImage{}).
+		Where("product_id = ? AND id <> ? AND is_primary", productID, target).
+		UpdateColumn("is_primary", false).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.ProductImage{}).Where("id = ?", target).
+		UpdateColumn("is_primary", true).Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// lockProduct блокирует товар, чтобы правки его картинок шли по очереди.
+func lockProduct(tx *gorm.DB, id uint) error {
+	var p models.Product
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&p, id).Error
+}
+
+func findProductImage(tx *gorm.DB, productID, id uint) (models.ProductImage, error) {
+	var img models.ProductImage
+	err := tx.Where("product_id = ?", productID).First(&img, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return img, errImageNotFound
+	}
+	return img, err
+}
+
+func respondProductImages(c *gin.Context, created bool, productID uint) {
+	var list []models.ProductImage
+	if err := config.DB.Where("product_id = ?", productID).
+		Scopes(orderImages).
+		Preload("Variants").
+		Find(&list).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.ProductImageDTO, 0, len(list))
+	for _, img := range list {
+		resp = append(resp, productImageToDTO(img))
+	}
+	if created {
+		utils.RespondCreated(c, resp)
+		return
+	}
+	utils.RespondOK(c, resp)
+}
+
+func respondImageError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondError(c, http.StatusNotFound, "product not found")
+		return
+	}
+	if errors.Is(err, errImageNotFound) {
+		utils.RespondError(c, http.StatusNotFound, "image not found")
+		return
+	}
+	if ie, ok := isImageInputErr(err); ok {
+		utils.RespondError(c, http.StatusBadRequest, ie.Error())
+		return
+	}
+	utils.RespondError(c, http.StatusInternalServerError, "db error")
+}
+
+// productImageParams разбирает id товара и, если wantImage, id картинки;
+// при ошибке сам отвечает 400.
+func productImageParams(c *gin.Context, wantImage bool) (productID, imageID uint, ok bool) {
+	productID, ok = utils.ParamID(c, "id")
+	if ok && wantImage {
+		imageID, ok = utils.ParamID(c, "imageId")
+	}
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+	}
+	return productID, imageID, ok
+}
+
+func AdminListProductImages(c *gin.Context) {
+	productID, _, ok := productImageParams(c, false)
+	if !ok {
+		return
+	}
+
+	var p models.Product
+	if err := config.DB.Select("id").First(&p, productID).Error; err != nil {
+		respondImageError(c, err)
+		return
+	}
+	respondProductImages(c, false, productID)
+}
+
+func AdminAddProductImage(c *gin.Context) {
+	var req dto.ProductImageCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	productID, _, ok := productImageParams(c, false)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		pos := int(count)
+		if req.Position != nil && *req.Position < pos {
+			pos = *req.Position
+			if err := tx.Model(&models.ProductImage{}).
+				Where("product_id = ? AND sort_order >= ?", productID, pos).
+				UpdateColumn("sort_order", gorm.Expr("sort_order + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		img := models.ProductImage{ProductID: productID, URL: req.URL, SortOrder: pos}
+		if err := tx.Omit(clause.Associations, "is_primary").Create(&img).Error; err != nil {
+			return err
+		}
+
+		var primaryID *uint
+		if req.IsPrimary {
+			primaryID = &img.ID
+		}
+		_, err := normalizeImages(tx, productID, primaryID)
+		return err
+	})
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+
+	images.Enqueue(req.URL)
+	respondProductImages(c, true, productID)
+}
+
+func AdminDeleteProductImage(c *gin.Context) {
+	productID, imageID, ok := productImageParams(c, true)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		img, err := findProductImage(tx, productID, imageID)
+		if err != nil {
+			return err
+		}
+		// сам файл остаётся в хранилище — его уберёт сборщик неиспользуемых файлов
+		if err := tx.Unscoped().Delete(&img).Error; err != nil {
+			return err
+		}
+		// если удалили основную, основной станет первая оставшаяся
+		_, err = normalizeImages(tx, productID, nil)
+		return err
+	})
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+
+	respondProductImages(c, false, productID)
+}
+
+func AdminSetPrimaryProductImage(c *gin.Context) {
+	productID, imageID, ok := productImageParams(c, true)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		img, err := findProductImage(tx, productID, imageID)
+		if err != nil {
+			return err
+		}
+		_, err = normalizeImages(tx, productID, &img.ID)
+		return err
+	})
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+
+	respondProductImages(c, false, productID)
+}
+
+func AdminReorderProductImages(c *gin.Context) {
+	var req dto.ProductImageOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	productID, _, ok := productImageParams(c, false)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		var ids []uint
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		// порядок должен перечислять все картинки товара ровно по разу
+		own := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			own[id] = true
+		}
+		if len(req.IDs) != len(ids) {
+			return &errImageInput{msg: "ids must list every image of the product"}
+		}
+		seen := make(map[uint]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if !own[id] {
+				return &errImageInput{msg: fmt.Sprintf("image %d does not belong to product", id)}
+			}
+			if seen[id] {
+				return &errImageInput{msg: fmt.Sprintf("duplicate image %d", id)}
+			}
+			seen[id] = true
+		}
+
+		for i, id := range req.IDs {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).
+				UpdateColumn("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		_, err := normalizeImages(tx, productID, nil)
+		return err
+	})
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+
+	respondProductImages(c, false, productID)
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"clen_shop/dto"
+	"clen_shop/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// errVariantInput — ошибка во входных данных вариантов (дубликаты, чужой id).
+type errVariantInput struct {
+	msg string
+}
+
+func (e *errVariantInput) Error() string { return e.msg }
+
+// tastesToVariantInputs превращает старый формат tastes: ["Шоколад", ...]
+// в варианты. Поля существующих вариантов с тем же названием сохраняются.
+func tastesToVariantInputs(tastes []string, existing []models.ProductTaste) []dto.ProductVariantInput {
+	out := make([]dto.ProductVariantInput, 0, len(tastes))
+	for i, t := range tastes {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		in := dto.ProductVariantInput{Name: t, SortOrder: i}
+		for _, ex := range existing {
+			if strings.EqualFold(ex.Name, t) {
+				id := ex.ID
+				active := ex.IsActive
+				in.ID = &id
+				in.SKU = ex.SKU
+				in.Barcode = ex.Barcode
+				in.Stock = ex.Stock
+				in.Price = ex.Price
+				in.IsActive = &active
+				break
+			}
+		}
+		out = append(out, in)
+	}
+	return out
+}
+
+// applyVariants приводит варианты товара к списку inputs: совпавшие (по id,
+// затем по названию) обновляются на месте, новые создаются, лишние удаляются.
+// Изменения остатков проводятся через складской журнал от имени actorID.
+// Возвращает актуальный список вариантов.
+func applyVariants(tx *gorm.DB, productID uint, existing []models.ProductTaste, inputs []dto.ProductVariantInput, actorID *uint) ([]models.ProductTaste, error) {
+	byID := make(map[uint]models.ProductTaste, len(existing))
+	for _, v := range existing {
+		byID[v.ID] = v
+	}
+
+	seenNames := make(map[string]bool, len(inputs))
+	kept := make(map[uint]bool, len(inputs))
+	result := make([]models.ProductTaste, 0, len(inputs))
+
+	for _, in := range inputs {
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if seenNames[key] {
+			return nil, &errVariantInput{msg: fmt.Sprintf("duplicate variant '%s'", name)}
+		}
+		seenNames[key] = true
+
+		var v models.ProductTaste
+		found := false
+		if in.ID != nil {
+			v, found = byID[*in.ID]
+			if !found {
+				return nil, &errVariantInput{msg: fmt.Sprintf("variant %d does not belong to product", *in.ID)}
+			}
+		} else {
+			for _, ex := range existing {
+				if !kept[ex.ID] && strings.EqualFold(ex.Name, name) {
+					v, found = ex, true
+					break
+				}
+			}
+		}
+		if !found {
+			v = models.ProductTaste{ProductID: productID, IsActive: true}
+		}
+
+		v.Name = name
+		v.SKU = strings.TrimSpace(in.SKU)
+		v.Barcode = strings.TrimSpace(in.Barcode)
+		v.Price = in.Price
+		v.SortOrder = in.SortOrder
+		if in.IsActive != nil {
+			v.IsActive = *in.IsActive
+		}
+
+		// остаток не пишем напрямую — только через журнал ниже
+		if err := tx.Omit("stock").Save(&v).Error; err != nil {
+			return nil, err
+		}
+
+		if in.Stock != v.Stock {
+			kind, reason := models.StockAdjustment, "правка товара"
+			if !found {
+				kind, reason = models.StockReceipt, "новый вариант"
+			}
+			vid, target := v.ID, in.Stock
+			rec, err := moveStock(tx, stockMove{
+				ProductID: productID,
+				VariantID: &vid,
+				Kind:      kind,
+				Target:    &target,
+				Reason:    reason,
+				ActorID:   actorID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			v.Stock = rec.Balance
+		}
+
+		kept[v.ID] = true
+		result = append(result, v)
+	}
+
+	for _, ex := range existing {
+		if kept[ex.ID] {
+			continue
+		}
+		if ex.Stock > 0 {
+			vid := ex.ID
+			if _, err := moveStock(tx, stockMove{
+				ProductID: productID,
+				VariantID: &vid,
+				Kind:      models.StockWriteOff,
+				Delta:     -ex.Stock,
+				Reason:    "вариант удалён",
+				ActorID:   actorID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Delete(&models.ProductTaste{}, ex.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// syncProductStock пересчитывает Product.Stock как сумму остатков активных
+// вариантов. Товары без вариантов не трогаются.
+func syncProductStock(tx *gorm.DB, productID uint) error {
+	return tx.Exec(`
+		UPDATE products SET stock = (
+			SELECT COALESCE(SUM(stock), 0) FROM product_tastes
+			WHERE product_id = products.id AND is_active AND deleted_at IS NULL
+		)
+		WHERE id = ? AND EXISTS (
+			SELECT 1 FROM product_tastes WHERE product_id = products.id AND deleted_at IS NULL
+		)`, productID).Error
+}
+
+// variantsStock — то же, что syncProductStock, но по уже загруженным вариантам.
+func variantsStock(variants []models.ProductTaste) int {
+	total := 0
+	for _, v := range variants {
+		if v.IsActive {
+			total += v.Stock
+		}
+	}
+	return total
+}
+
+func isVariantInputErr(err error) (*errVariantInput, bool) {
+	var ve *errVariantInput
+	ok := errors.As(err, &ve)
+	return ve, ok
+}
+
+func variantToDTO(p models.Product, v models.ProductTaste) dto.ProductVariantDTO {
+	return dto.ProductVariantDTO{
+		ID:        v.ID,
+		Name:      v.Name,
+		SKU:       v.SKU,
+		Barcode:   v.Barcode,
+		Stock:     v.Stock,
+		Price:     productUnitPrice(p, v.Name),
+		HasPrice:  v.Price != nil,
+		IsActive:  v.IsActive,
+		SortOrder: v.SortOrder,
+	}
+}
@@ -0,0 +1,527 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// promoError — промокод нельзя применить; текст показывается покупателю.
+type promoError struct {
+	msg string
+}
+
+func (e *promoError) Error() string { return e.msg }
+
+// promoLine — позиция, к которой может относиться скидка.
+type promoLine struct {
+	ProductID  uint
+	CategoryID uint
+	Total      int64
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func findPromo(db *gorm.DB, code string, lock bool) (models.PromoCode, error) {
+	var promo models.PromoCode
+	q := db.Preload("Categories").Preload("Products").Where("code = ?", normalizePromoCode(code))
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := q.First(&promo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return promo, &promoError{msg: "промокод не найден"}
+	}
+	return promo, err
+}
+
+// evaluatePromo проверяет условия промокода и считает скидку в тенге.
+// Минимальная сумма сравнивается со всем заказом, а скидка считается только
+// с позиций, попадающих в категории/товары промокода.
+func evaluatePromo(db *gorm.DB, promo models.PromoCode, lines []promoLine, userID *uint, now time.Time) (int64, error) {
+	if !promo.IsActive {
+		return 0, &promoError{msg: "промокод не активен"}
+	}
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return 0, &promoError{msg: "промокод ещё не действует"}
+	}
+	if promo.EndsAt != nil && !now.Before(*promo.EndsAt) {
+		return 0, &promoError{msg: "срок действия промокода истёк"}
+	}
+	if promo.UsageLimit != nil && promo.UsedCount >= *promo.UsageLimit {
+		return 0, &promoError{msg: "промокод больше не действует"}
+	}
+
+	if promo.PerUserLimit != nil {
+		if userID == nil {
+			return 0, &promoError{msg: "войдите в аккаунт, чтобы использовать этот промокод"}
+		}
+		var used int64
+		if err := db.Model(&models.PromoRedemption{}).
+			Where("promo_code_id = ? AND user_id = ?", promo.ID, *userID).
+			Count(&used).Error; err != nil {
+			return 0, err
+		}
+		if used >= int64(*promo.PerUserLimit) {
+			return 0, &promoError{msg: "вы уже использовали этот промокод"}
+		}
+	}
+
+	var subtotal, eligible int64
+	scoped := len(promo.Categories) > 0 || len(promo.Products) > 0
+	for _, l := range lines {
+		subtotal += l.Total
+		if !scoped || promoCovers(promo, l) {
+			eligible += l.Total
+		}
+	}
+
+	if subtotal < promo.MinOrderTotal {
+		return 0, &promoError{msg: fmt.Sprintf("минимальная сумма заказа для промокода — %d ₸", promo.MinOrderTotal)}
+	}
+	if eligible == 0 {
+		return 0, &promoError{msg: "в корзине нет товаров, на которые действует промокод"}
+	}
+
+	var discount int64
+	switch promo.Kind {
+	case models.PromoPercent:
+		discount = eligible * promo.Value / 100
+		if promo.MaxDiscount != nil && discount > *promo.MaxDiscount {
+			discount = *promo.MaxDiscount
+		}
+	case models.PromoFixed:
+		discount = promo.Value
+	}
+	if discount > eligible {
+		discount = eligible
+	}
+	return discount, nil
+}
+
+func promoCovers(promo models.PromoCode, l promoLine) bool {
+	for _, p := range promo.Products {
+		if p.ID == l.ProductID {
+			return true
+		}
+	}
+	for _, cat := range promo.Categories {
+		if cat.ID == l.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// redeemPromo фиксирует использование промокода заказом.
+func redeemPromo(tx *gorm.DB, promo models.PromoCode, orderID uint, userID *uint, discount int64) error {
+	if err := tx.Create(&models.PromoRedemption{
+		PromoCodeID: promo.ID,
+		OrderID:     orderID,
+		UserID:      userID,
+		Discount:    discount,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PromoCode{}).
+		Where("id = ?", promo.ID).
+		UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error
+}
+
+// releasePromo — отменённый заказ не должен расходовать лимит промокода.
+func releasePromo(tx *gorm.DB, orderID uint) error {
+	var r models.PromoRedemption
+	err := tx.Where("order_id = ?", orderID).First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Delete(&r).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PromoCode{}).
+		Where("id = ? AND used_count > 0", r.PromoCodeID).
+		UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// ApplyPromoToCart — предпросмотр скидки по текущей корзине, без резервирования кода.
+func ApplyPromoToCart(c *gin.Context) {
+	var req dto.PromoApplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	cart, err := loadCart(c, false)
+	if err != nil {
+		if errors.Is(err, errCartNotFound) {
+			utils.RespondError(c, http.StatusBadRequest, "cart is empty")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var full models.Cart
+	if err := preloadCartItems(config.DB).First(&full, cart.ID).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	lines := make([]promoLine, 0, len(full.Items))
+	for _, it := range full.Items {
+		if checkCartLine(it.Product, it.Taste, it.Quantity) != "" {
+			continue
+		}
+		lines = append(lines, promoLine{
+			ProductID:  it.ProductID,
+			CategoryID: it.Product.CategoryID,
+			Total:      productUnitPrice(it.Product, it.Taste) * int64(it.Quantity),
+		})
+	}
+	if len(lines) == 0 {
+		utils.RespondError(c, http.StatusBadRequest, "cart is empty")
+		return
+	}
+
+	promo, err := findPromo(config.DB, req.Code, false)
+	var discount int64
+	if err == nil {
+		discount, err = evaluatePromo(config.DB, promo, lines, currentUserID(c), time.Now())
+	}
+	if err != nil {
+		var pe *promoError
+		if errors.As(err, &pe) {
+			utils.RespondError(c, http.StatusUnprocessableEntity, pe.msg)
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var subtotal int64
+	for _, l := range lines {
+		subtotal += l.Total
+	}
+
+	utils.RespondOK(c, dto.PromoApplyResponse{
+		Code:     promo.Code,
+		Subtotal: subtotal,
+		Discount: discount,
+		Total:    subtotal - discount,
+	})
+}
+
+func AdminListPromoCodes(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.PromoCode{})
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		db = db.Where("code ILIKE ?", "%"+q+"%")
+	}
+	if active := c.Query("active"); active == "true" {
+		db = db.Where("is_active = ?", true)
+	} else if active == "false" {
+		db = db.Where("is_active = ?", false)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var items []models.PromoCode
+	if err := db.Preload("Categories").Preload("Products").
+		Order("created_at desc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Find(&items).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.PromoCodeResponse, 0, len(items))
+	for _, it := range items {
+		resp = append(resp, promoToResp(it))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminGetPromoCode(c *gin.Context) {
+	promoID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var promo models.PromoCode
+	if err := config.DB.Preload("Categories").Preload("Products").First(&promo, promoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "promo code not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, promoToResp(promo))
+}
+
+func AdminCreatePromoCode(c *gin.Context) {
+	var req dto.PromoCodeCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	promo := models.PromoCode{
+		Code:          normalizePromoCode(req.Code),
+		Description:   req.Description,
+		Kind:          models.PromoKind(req.Kind),
+		Value:         req.Value,
+		MaxDiscount:   req.MaxDiscount,
+		MinOrderTotal: req.MinOrderTotal,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		UsageLimit:    req.UsageLimit,
+		PerUserLimit:  req.PerUserLimit,
+		IsActive:      true,
+	}
+	if req.IsActive != nil {
+		promo.IsActive = *req.IsActive
+	}
+
+	if msg := checkPromoRules(promo); msg != "" {
+		utils.RespondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&promo).Error; err != nil {
+			return err
+		}
+		return setPromoScope(tx, &promo, &req.CategoryIDs, &req.ProductIDs)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "promo code already exists")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, promoToResp(promo))
+}
+
+func AdminUpdatePromoCode(c *gin.Context) {
+	promoID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.PromoCodeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var promo models.PromoCode
+	if err := config.DB.Preload("Categories").Preload("Products").First(&promo, promoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "promo code not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if req.Description != nil {
+		promo.Description = *req.Description
+	}
+	if req.Kind != nil {
+		promo.Kind = models.PromoKind(*req.Kind)
+	}
+	if req.Value != nil {
+		promo.Value = *req.Value
+	}
+	if req.MaxDiscount != nil {
+		promo.MaxDiscount = req.MaxDiscount
+		if *req.MaxDiscount == 0 {
+			promo.MaxDiscount = nil
+		}
+	}
+	if req.MinOrderTotal != nil {
+		promo.MinOrderTotal = *req.MinOrderTotal
+	}
+	if req.StartsAt.Set {
+		promo.StartsAt = req.StartsAt.Value
+	}
+	if req.EndsAt.Set {
+		promo.EndsAt = req.EndsAt.Value
+	}
+	if req.UsageLimit != nil {
+		promo.UsageLimit = req.UsageLimit
+		if *req.UsageLimit == 0 {
+			promo.UsageLimit = nil
+		}
+	}
+	if req.PerUserLimit != nil {
+		promo.PerUserLimit = req.PerUserLimit
+		if *req.PerUserLimit == 0 {
+			promo.PerUserLimit = nil
+		}
+	}
+	if req.IsActive != nil {
+		promo.IsActive = *req.IsActive
+	}
+
+	if msg := checkPromoRules(promo); msg != "" {
+		utils.RespondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&promo).Error; err != nil {
+			return err
+		}
+		return setPromoScope(tx, &promo, req.CategoryIDs, req.ProductIDs)
+	})
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, promoToResp(promo))
+}
+
+func AdminDeletePromoCode(c *gin.Context) {
+	promoID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := config.DB.Delete(&models.PromoCode{}, promoID).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	utils.RespondOK(c, gin.H{"deleted": true})
+}
+
+func checkPromoRules(p models.PromoCode) string {
+	if p.Kind == models.PromoPercent && p.Value > 100 {
+		return "percent discount cannot exceed 100"
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return "ends_at must be after starts_at"
+	}
+	return ""
+}
+
+// setPromoScope заменяет категории/товары промокода; nil — не трогать.
+func setPromoScope(tx *gorm.DB, promo *models.PromoCode, categoryIDs, productIDs *[]uint) error {
+	if categoryIDs != nil {
+		cats := make([]models.Category, 0, len(*categoryIDs))
+		if len(*categoryIDs) > 0 {
+			if err := tx.Where("id IN ?", *categoryIDs).Find(&cats).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(promo).Association("Categories").Replace(cats); err != nil {
+			return err
+		}
+		promo.Categories = cats
+	}
+	if productIDs != nil {
+		products := make([]models.Product, 0, len(*productIDs))
+		if len(*productIDs) > 0 {
+			if err := tx.Where("id IN ?", *productIDs).Find(&products).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(promo).Association("Products").Replace(products); err != nil {
+			return err
+		}
+		promo.Products = products
+	}
+	return nil
+}
+
+func promoToResp(p models.PromoCode) dto.PromoCodeResponse {
+	resp := dto.PromoCodeResponse{
+		ID:            p.ID,
+		Code:          p.Code,
+		Description:   p.Description,
+		Kind:          string(p.Kind),
+		Value:         p.Value,
+		MaxDiscount:   p.MaxDiscount,
+		MinOrderTotal: p.MinOrderTotal,
+		StartsAt:      p.StartsAt,
+		EndsAt:        p.EndsAt,
+		UsageLimit:    p.UsageLimit,
+		PerUserLimit:  p.PerUserLimit,
+		UsedCount:     p.UsedCount,
+		IsActive:      p.IsActive,
+		CategoryIDs:   make([]uint, 0, len(p.Categories)),
+		ProductIDs:    make([]uint, 0, len(p.Products)),
+	}
+	for _, cat := range p.Categories {
+		resp.CategoryIDs = append(resp.CategoryIDs, cat.ID)
+	}
+	for _, pr := range p.Products {
+		resp.ProductIDs = append(resp.ProductIDs, pr.ID)
+	}
+	return resp
+}
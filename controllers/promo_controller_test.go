@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"clen_shop/models"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestEvaluatePromo(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	i64 := func(v int64) *int64 { return &v }
+	intp := func(v int) *int { return &v }
+
+	base := models.PromoCode{Kind: models.PromoPercent, Value: 10, IsActive: true}
+	with := func(f func(*models.PromoCode)) models.PromoCode {
+		p := base
+		f(&p)
+		return p
+	}
+
+	lines := []promoLine{
+		{ProductID: 1, CategoryID: 10, Total: 6000},
+		{ProductID: 2, CategoryID: 20, Total: 4000},
+	}
+
+	tests := []struct {
+		name    string
+		promo   models.PromoCode
+		lines   []promoLine
+		want    int64
+		wantErr bool
+	}{
+		{name: "percent of whole order", promo: base, lines: lines, want: 1000},
+		{name: "inactive", promo: with(func(p *models.PromoCode) { p.IsActive = false }), lines: lines, wantErr: true},
+		{name: "not started", promo: with(func(p *models.PromoCode) { p.StartsAt = &future }), lines: lines, wantErr: true},
+		{name: "started", promo: with(func(p *models.PromoCode) { p.StartsAt = &past }), lines: lines, want: 1000},
+		{name: "expired", promo: with(func(p *models.PromoCode) { p.EndsAt = &past }), lines: lines, wantErr: true},
+		{name: "ends exactly now", promo: with(func(p *models.PromoCode) { p.EndsAt = &now }), lines: lines, wantErr: true},
+		{name: "usage limit reached", promo: with(func(p *models.PromoCode) {
+			p.UsageLimit, p.UsedCount = intp(5), 5
+		}), lines: lines, wantErr: true},
+		{name: "usage limit left", promo: with(func(p *models.PromoCode) {
+			p.UsageLimit, p.UsedCount = intp(5), 4
+		}), lines: lines, want: 1000},
+		{name: "per-user limit needs login", promo: with(func(p *models.PromoCode) { p.PerUserLimit = intp(1) }), lines: lines, wantErr: true},
+		{name: "min order total not met", promo: with(func(p *models.PromoCode) { p.MinOrderTotal = 10001 }), lines: lines, wantErr: true},
+		{name: "min order total met", promo: with(func(p *models.PromoCode) { p.MinOrderTotal = 10000 }), lines: lines, want: 1000},
+		{name: "percent capped by max discount", promo: with(func(p *models.PromoCode) { p.MaxDiscount = i64(300) }), lines: lines, want: 300},
+		{name: "fixed", promo: with(func(p *models.PromoCode) { p.Kind, p.Value = models.PromoFixed, 1500 }), lines: lines, want: 1500},
+		{name: "fixed capped by eligible total", promo: with(func(p *models.PromoCode) {
+			p.Kind, p.Value = models.PromoFixed, 5000
+			p.Products = []models.Product{{Model: gorm.Model{ID: 2}}}
+		}), lines: lines, want: 4000},
+		{name: "scoped by product", promo: with(func(p *models.PromoCode) {
+			p.Products = []models.Product{{Model: gorm.Model{ID: 1}}}
+		}), lines: lines, want: 600},
+		{name: "scoped by category", promo: with(func(p *models.PromoCode) {
+			p.Categories = []models.Category{{Model: gorm.Model{ID: 20}}}
+		}), lines: lines, want: 400},
+		{name: "min order total counts the whole order", promo: with(func(p *models.PromoCode) {
+			p.MinOrderTotal = 8000
+			p.Categories = []models.Category{{Model: gorm.Model{ID: 20}}}
+		}), lines: lines, want: 400},
+		{name: "nothing eligible", promo: with(func(p *models.PromoCode) {
+			p.Products = []models.Product{{Model: gorm.Model{ID: 3}}}
+		}), lines: lines, wantErr: true},
+		{name: "empty cart", promo: base, lines: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// без лимита на пользователя evaluatePromo не обращается к базе
+			got, err := evaluatePromo(nil, tt.promo, tt.lines, nil, now)
+			if tt.wantErr {
+				var pe *promoError
+				if !errors.As(err, &pe) {
+					t.Fatalf("got (%d, %v), want promoError", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("discount = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// recalcProductRating пересчитывает средний рейтинг и число одобренных отзывов.
+func recalcProductRating(tx *gorm.DB, productID uint) error {
+	return tx.Exec(`
+		UPDATE products SET
+			rating_avg = COALESCE((SELECT AVG(rating) FROM reviews
+				WHERE product_id = products.id AND status = ? AND deleted_at IS NULL), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews
+				WHERE product_id = products.id AND status = ? AND deleted_at IS NULL)
+		WHERE id = ?`, models.ReviewApproved, models.ReviewApproved, productID).Error
+}
+
+func CreateReview(c *gin.Context) {
+	var req dto.ReviewCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	userID := currentUserID(c)
+	if userID == nil {
+		utils.RespondError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var p models.Product
+	if err := config.DB.Preload("Tastes").
+		Where("slug = ? AND is_active = ?", c.Param("slug"), true).
+		First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	taste := strings.TrimSpace(req.Taste)
+	if taste != "" {
+		t, ok := findProductTaste(p, taste)
+		if !ok {
+			utils.RespondError(c, http.StatusBadRequest, "unknown taste")
+			return
+		}
+		taste = t.Name
+	}
+
+	review := models.Review{
+		ProductID: p.ID,
+		UserID:    *userID,
+		Rating:    req.Rating,
+		Text:      strings.TrimSpace(req.Text),
+		Taste:     taste,
+		Status:    models.ReviewPending,
+	}
+
+	if err := config.DB.Create(&review).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, http.StatusConflict, "you have already reviewed this product")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, reviewToResp(review, true))
+}
+
+func ListProductReviews(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	var p models.Product
+	if err := config.DB.Select("id").Where("slug = ?", c.Param("slug")).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	db := config.DB.Model(&models.Review{}).
+		Where("product_id = ? AND status = ?", p.ID, models.ReviewApproved)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var reviews []models.Review
+	if err := db.Preload("User").
+		Order("created_at desc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Find(&reviews).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.ReviewResponse, 0, len(reviews))
+	for _, r := range reviews {
+		resp = append(resp, reviewToResp(r, false))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminListReviews(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	status := c.DefaultQuery("status", string(models.ReviewPending))
+
+	db := config.DB.Model(&models.Review{})
+	if status != "all" {
+		db = db.Where("status = ?", status)
+	}
+	if pid := c.Query("product_id"); pid != "" {
+		db = db.Where("product_id = ?", pid)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var reviews []models.Review
+	if err := db.Preload("User").Preload("Product").
+		Order("created_at asc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Find(&reviews).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.ReviewResponse, 0, len(reviews))
+	for _, r := range reviews {
+		resp = append(resp, reviewToResp(r, true))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminModerateReview(c *gin.Context) {
+	reviewID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.ReviewModerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	var review models.Review
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("User").Preload("Product").First(&review, reviewID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		review.Status = models.ReviewStatus(req.Status)
+		review.ModeratedBy = currentUserID(c)
+		review.ModeratedAt = &now
+		review.ModerationNote = strings.TrimSpace(req.Note)
+
+		if err := tx.Model(&review).Select("Status", "ModeratedBy", "ModeratedAt", "ModerationNote").
+			Updates(&review).Error; err != nil {
+			return err
+		}
+		return recalcProductRating(tx, review.ProductID)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "review not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, reviewToResp(review, true))
+}
+
+func AdminDeleteReview(c *gin.Context) {
+	reviewID, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := tx.First(&review, reviewID).Error; err != nil {
+			return err
+		}
+		// удаляем окончательно, чтобы пользователь мог оставить отзыв заново
+		if err := tx.Unscoped().Delete(&review).Error; err != nil {
+			return err
+		}
+		return recalcProductRating(tx, review.ProductID)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "review not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, gin.H{"deleted": true})
+}
+
+func reviewToResp(r models.Review, admin bool) dto.ReviewResponse {
+	resp := dto.ReviewResponse{
+		ID:         r.ID,
+		ProductID:  r.ProductID,
+		AuthorName: r.User.Name,
+		Rating:     r.Rating,
+		Text:       r.Text,
+		Taste:      r.Taste,
+		CreatedAt:  r.CreatedAt,
+	}
+	if admin {
+		resp.ProductName = r.Product.Name
+		resp.Status = string(r.Status)
+		resp.Note = r.ModerationNote
+		resp.ModeratedAt = r.ModeratedAt
+	}
+	return resp
+}
+
+func roundRating(v float64) float64 {
+	return math.Round(v*100) / 100
+}
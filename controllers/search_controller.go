@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/search"
+	"clen_shop/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchSuggest — подсказки для строки поиска: несколько товаров, категорий
+// и вкусов. Все запросы идут по индексам (tsvector и pg_trgm), без сканов таблиц.
+func SearchSuggest(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+
+	limit := 5
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 10 {
+			limit = n
+		}
+	}
+
+	resp := dto.SuggestResponse{
+		Query:      q,
+		Products:   []dto.SuggestProduct{},
+		Categories: []dto.SuggestCategory{},
+		Tastes:     []dto.SuggestTaste{},
+	}
+
+	// на одну букву подсказывать бессмысленно, а индекс триграмм её не ускорит
+	if utf8.RuneCountInString(q) < 2 {
+		utils.RespondOK(c, resp)
+		return
+	}
+
+	products := config.DB.Model(&models.Product{}).
+		Select(`products.id, products.name, products.slug, `+effectivePriceSQL+` AS price,
+			COALESCE((SELECT pi.url FROM product_images pi
+				WHERE pi.product_id = products.id AND pi.deleted_at IS NULL
+				ORDER BY pi.is_primary DESC, pi.sort_order ASC LIMIT 1), '') AS image_url`).
+		Where("products.is_active = ?", true).
+		Limit(limit)
+
+	if err := search.Prefix(products.Session(&gorm.Session{}), q).Scan(&resp.Products).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	// опечатки и чужая раскладка — тот же нечёткий поиск, что и в каталоге
+	if len(resp.Products) == 0 {
+		if err := search.OrderByRank(search.Fuzzy(products, q), q, true).Scan(&resp.Products).Error; err != nil {
+			utils.RespondError(c, http.StatusInternalServerError, "db error")
+			return
+		}
+	}
+
+	like := "%" + q + "%"
+
+	if err := config.DB.Model(&models.Category{}).
+		Select("id, name, slug").
+		Where("name ILIKE ?", like).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "similarity(name, ?) DESC", Vars: []interface{}{q}, WithoutParentheses: true}}).
+		Limit(limit).
+		Scan(&resp.Categories).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	if err := config.DB.Model(&models.ProductTaste{}).
+		Select("MIN(product_tastes.name) AS name, COUNT(DISTINCT product_tastes.product_id) AS product_count").
+		Joins("JOIN products ON products.id = product_tastes.product_id AND products.deleted_at IS NULL").
+		Where("products.is_active = ? AND product_tastes.is_active = ?", true, true).
+		Where("product_tastes.name ILIKE ?", like).
+		Group("lower(product_tastes.name)").
+		Order("product_count DESC, name ASC").
+		Limit(limit).
+		Scan(&resp.Tastes).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	utils.RespondOK(c, resp)
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"clen_shop/validators"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errInsufficientStock = errors.New("insufficient stock")
+
+// stockMove — одно движение по складу. Delta со знаком: + приход, − расход.
+type stockMove struct {
+	ProductID uint
+	VariantID *uint
+	BatchID   *uint // движение по конкретной партии; иначе партии выбираются по FEFO
+	Kind      models.StockMovementKind
+	Delta     int
+	// Target — если задан, Delta считается под блокировкой как Target − остаток:
+	// продажа между чтением остатка и правкой не перезатрётся
+	Target    *int
+	Reason    string
+	ActorID   *uint
+	Reference string
+}
+
+// moveStock — единственное место, где меняется остаток: блокирует строку
+// товара/варианта, проверяет, что остаток не уходит в минус, обновляет его,
+// дописывает запись в журнал и раскладывает движение по партиям.
+func moveStock(tx *gorm.DB, m stockMove) (models.StockMovement, error) {
+	var current int
+
+	if m.VariantID != nil {
+		var v models.ProductTaste
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", m.ProductID).
+			First(&v, *m.VariantID).Error; err != nil {
+			return models.StockMovement{}, err
+		}
+		current = v.Stock
+	} else {
+		var p models.Product
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&p, m.ProductID).Error; err != nil {
+			return models.StockMovement{}, err
+		}
+		current = p.Stock
+	}
+
+	if m.Target != nil {
+		m.Delta = *m.Target - current
+		if m.Delta == 0 {
+			return models.StockMovement{Balance: current}, nil
+		}
+	}
+
+	if err := ensureOpeningBalance(tx, m.ProductID, m.VariantID, current); err != nil {
+		return models.StockMovement{}, err
+	}
+
+	balance := current + m.Delta
+	if balance < 0 {
+		return models.StockMovement{}, errInsufficientStock
+	}
+
+	if m.VariantID != nil {
+		if err := tx.Unscoped().Model(&models.ProductTaste{}).
+			Where("id = ?", *m.VariantID).
+			UpdateColumn("stock", balance).Error; err != nil {
+			return models.StockMovement{}, err
+		}
+		if err := syncProductStock(tx, m.ProductID); err != nil {
+			return models.StockMovement{}, err
+		}
+	} else {
+		if err := tx.Unscoped().Model(&models.Product{}).
+			Where("id = ?", m.ProductID).
+			UpdateColumn("stock", balance).Error; err != nil {
+			return models.StockMovement{}, err
+		}
+	}
+
+	rec := models.StockMovement{
+		ProductID: m.ProductID,
+		VariantID: m.VariantID,
+		Kind:      m.Kind,
+		Delta:     m.Delta,
+		Balance:   balance,
+		Reason:    m.Reason,
+		ActorID:   m.ActorID,
+		Reference: m.Reference,
+	}
+	if err := tx.Create(&rec).Error; err != nil {
+		return models.StockMovement{}, err
+	}
+	if err := applyBatches(tx, m, rec); err != nil {
+		return models.StockMovement{}, err
+	}
+	return rec, nil
+}
+
+func movementScope(db *gorm.DB, productID uint, variantID *uint) *gorm.DB {
+	db = db.Model(&models.StockMovement{}).Where("product_id = ?", productID)
+	if variantID != nil {
+		return db.Where("variant_id = ?", *variantID)
+	}
+	return db.Where("variant_id IS NULL")
+}
+
+// ensureOpeningBalance: остатки, заведённые до появления журнала, попадают
+// в него одной начальной корректировкой — так сумма журнала сходится с остатком.
+func ensureOpeningBalance(tx *gorm.DB, productID uint, variantID *uint, current int) error {
+	if current == 0 {
+		return nil
+	}
+	var count int64
+	if err := movementScope(tx, productID, variantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.StockMovement{
+		ProductID: productID,
+		VariantID: variantID,
+		Kind:      models.StockAdjustment,
+		Delta:     current,
+		Balance:   current,
+		Reason:    "начальный остаток",
+	}).Error
+}
+
+// ledgerBalance — остаток по журналу.
+func ledgerBalance(tx *gorm.DB, productID uint, variantID *uint) (int, error) {
+	var sum int
+	err := movementScope(tx, productID, variantID).
+		Select("COALESCE(SUM(delta), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+func currentUserID(c *gin.Context) *uint {
+	if uid, ok := c.Get("userID"); ok {
+		id := uid.(uint)
+		return &id
+	}
+	return nil
+}
+
+func AdminListStockMovements(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	page, limit := utils.GetPage(c)
+
+	db := config.DB.Model(&models.StockMovement{}).Where("product_id = ?", id)
+
+	if vid := c.Query("variant_id"); vid != "" {
+		if v, err := strconv.Atoi(vid); err == nil && v > 0 {
+			db = db.Where("variant_id = ?", v)
+		}
+	}
+	if kind := c.Query("kind"); kind != "" {
+		db = db.Where("kind IN ?", strings.Split(kind, ","))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	var items []models.StockMovement
+	if err := db.Order("id desc").Limit(limit).Offset(utils.Offset(page, limit)).Find(&items).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	resp := make([]dto.StockMovementResponse, 0, len(items))
+	for _, m := range items {
+		resp = append(resp, stockMovementToResp(m))
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": resp,
+	})
+}
+
+func AdminCreateStockMovement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.StockMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := validators.Validate.Struct(req); err != nil {
+		errorsMap := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsMap[e.Field()] = fmt.Sprintf("не проходит поле '%s'", e.Tag())
+		}
+		utils.RespondValidation(c, errorsMap)
+		return
+	}
+
+	kind := models.StockMovementKind(req.Kind)
+	delta := req.Quantity
+	switch kind {
+	case models.StockReceipt, models.StockReturn:
+		if delta < 0 {
+			utils.RespondError(c, http.StatusBadRequest, "quantity must be positive")
+			return
+		}
+	case models.StockWriteOff:
+		if delta < 0 {
+			utils.RespondError(c, http.StatusBadRequest, "quantity must be positive")
+			return
+		}
+		delta = -delta
+	}
+
+	var rec models.StockMovement
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var p models.Product
+		if err := tx.Preload("Tastes").First(&p, id).Error; err != nil {
+			return err
+		}
+		// у товара с вариантами остаток ведётся только по вариантам
+		if len(p.Tastes) > 0 && req.VariantID == nil {
+			return &errVariantInput{msg: "variant_id is required for product with variants"}
+		}
+
+		rec, err = moveStock(tx, stockMove{
+			ProductID: p.ID,
+			VariantID: req.VariantID,
+			Kind:      kind,
+			Delta:     delta,
+			Reason:    strings.TrimSpace(req.Reason),
+			ActorID:   currentUserID(c),
+			Reference: strings.TrimSpace(req.Reference),
+		})
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product or variant not found")
+			return
+		}
+		if ve, ok := isVariantInputErr(err); ok {
+			utils.RespondError(c, http.StatusBadRequest, ve.Error())
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			utils.RespondError(c, http.StatusConflict, "stock cannot become negative")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondCreated(c, stockMovementToResp(rec))
+}
+
+// AdminReconcileStock сверяет сохранённые остатки с журналом и,
+// если они разошлись, выставляет остаток по журналу.
+func AdminReconcileStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	type drift struct {
+		VariantID *uint `json:"variant_id"`
+		Stored    int   `json:"stored"`
+		Ledger    int   `json:"ledger"`
+	}
+	fixed := make([]drift, 0)
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var p models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tastes").First(&p, id).Error; err != nil {
+			return err
+		}
+
+		if len(p.Tastes) == 0 {
+			if err := ensureOpeningBalance(tx, p.ID, nil, p.Stock); err != nil {
+				return err
+			}
+			sum, err := ledgerBalance(tx, p.ID, nil)
+			if err != nil {
+				return err
+			}
+			if sum != p.Stock {
+				fixed = append(fixed, drift{Stored: p.Stock, Ledger: sum})
+				return tx.Model(&p).UpdateColumn("stock", sum).Error
+			}
+			return nil
+		}
+
+		for _, v := range p.Tastes {
+			vid := v.ID
+			if err := ensureOpeningBalance(tx, p.ID, &vid, v.Stock); err != nil {
+				return err
+			}
+			sum, err := ledgerBalance(tx, p.ID, &vid)
+			if err != nil {
+				return err
+			}
+			if sum != v.Stock {
+				fixed = append(fixed, drift{VariantID: &vid, Stored: v.Stock, Ledger: sum})
+				if err := tx.Model(&v).UpdateColumn("stock", sum).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return syncProductStock(tx, p.ID)
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "product not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	utils.RespondOK(c, gin.H{"fixed": fixed})
+}
+
+func stockMovementToResp(m models.StockMovement) dto.StockMovementResponse {
+	return dto.StockMovementResponse{
+		ID:        m.ID,
+		ProductID: m.ProductID,
+		VariantID: m.VariantID,
+		Kind:      string(m.Kind),
+		Delta:     m.Delta,
+		Balance:   m.Balance,
+		Reason:    m.Reason,
+		ActorID:   m.ActorID,
+		Reference: m.Reference,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/models"
+	"clen_shop/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Корзина (/admin/trash) — мягко удалённые товары и категории. Из неё запись
+// можно восстановить или удалить окончательно.
+const (
+	trashProducts   = "products"
+	trashCategories = "categories"
+)
+
+// errTrashConflict — восстановить или удалить запись сейчас нельзя.
+type errTrashConflict struct {
+	msg    string
+	extras gin.H
+}
+
+func (e *errTrashConflict) Error() string { return e.msg }
+
+func respondTrashError(c *gin.Context, err error) {
+	var tc *errTrashConflict
+	if errors.As(err, &tc) {
+		if tc.extras != nil {
+			utils.RespondError(c, http.StatusConflict, tc.msg, tc.extras)
+			return
+		}
+		utils.RespondError(c, http.StatusConflict, tc.msg)
+		return
+	}
+	if he, ok := isCategoryHierarchyErr(err); ok {
+		utils.RespondError(c, http.StatusConflict, he.Error())
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondError(c, http.StatusNotFound, "not found in trash")
+		return
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		utils.RespondError(c, http.StatusConflict, "slug is already taken")
+		return
+	}
+	utils.RespondError(c, http.StatusInternalServerError, "db error")
+}
+
+func AdminListTrash(c *gin.Context) {
+	page, limit := utils.GetPage(c)
+
+	kind := c.DefaultQuery("type", trashProducts)
+	if kind != trashProducts && kind != trashCategories {
+		utils.RespondError(c, http.StatusBadRequest, "unknown type")
+		return
+	}
+
+	db := config.DB.Table(kind + " t").Where("t.deleted_at IS NOT NULL")
+	if q := c.Query("q"); q != "" {
+		db = db.Where("t.name ILIKE ? OR t.slug ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+
+	items := []dto.TrashItem{}
+	if err := db.Select(`t.id, t.name, t.slug, t.deleted_at,
+		EXISTS (SELECT 1 FROM ` + kind + ` a WHERE a.slug = t.slug AND a.deleted_at IS NULL) AS slug_taken`).
+		Order("t.deleted_at desc").
+		Limit(limit).
+		Offset(utils.Offset(page, limit)).
+		Scan(&items).Error; err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "db error")
+		return
+	}
+	for i := range items {
+		items[i].Type = kind
+	}
+
+	utils.RespondOK(c, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"items": items,
+	})
+}
+
+func AdminRestoreTrash(c *gin.Context) {
+	var restore func(tx *gorm.DB, id uint) error
+	switch c.Param("type") {
+	case trashProducts:
+		restore = restoreProduct
+	case trashCategories:
+		restore = restoreCategory
+	default:
+		utils.RespondError(c, http.StatusBadRequest, "unknown type")
+		return
+	}
+
+	id, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return restore(tx, id)
+	}); err != nil {
+		respondTrashError(c, err)
+		return
+	}
+
+	invalidateCategoryTree()
+	utils.RespondOK(c, gin.H{"restored": true})
+}
+
+func restoreProduct(tx *gorm.DB, id uint) error {
+	var p models.Product
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&p, id).Error; err != nil {
+		return err
+	}
+
+	var cat models.Category
+	if err := tx.Select("id").First(&cat, p.CategoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &errTrashConflict{msg: "restore the product's category first",
+				extras: gin.H{"category_id": p.CategoryID}}
+		}
+		return err
+	}
+
+	// занятый slug упрётся в уникальный индекс и вернётся как ErrDuplicatedKey
+	return tx.Unscoped().Model(&p).Update("deleted_at", nil).Error
+}
+
+func restoreCategory(tx *gorm.DB, id uint) error {
+	var cat models.Category
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&cat, id).Error; err != nil {
+		return err
+	}
+
+	// родитель мог быть удалён или дерево стало глубже после удаления
+	if err := checkCategoryParent(tx, cat.ID, cat.ParentID); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Model(&cat).Update("deleted_at", nil).Error
+}
+
+func AdminPurgeTrash(c *gin.Context) {
+	var purge func(tx *gorm.DB, id uint) error
+	switch c.Param("type") {
+	case trashProducts:
+		purge = purgeProduct
+	case trashCategories:
+		purge = purgeCategory
+	default:
+		utils.RespondError(c, http.StatusBadRequest, "unknown type")
+		return
+	}
+
+	id, ok := utils.ParamID(c, "id")
+	if !ok {
+		utils.RespondError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return purge(tx, id)
+	}); err != nil {
+		respondTrashError(c, err)
+		return
+	}
+
+	utils.RespondOK(c, gin.H{"purged": true})
+}
+
+// purgeProduct удаляет товар окончательно. Картинки, варианты, значения
+// атрибутов и отзывы уходят каскадом; журналы склада и цен остаются как история,
+// а файлы картинок подберёт сборщик неиспользуемых файлов.
+func purgeProduct(tx *gorm.DB, id uint) error {
+	var p models.Product
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&p, id).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM promo_code_products WHERE product_id = ?", p.ID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&p).Error
+}
+
+func purgeCategory(tx *gorm.DB, id uint) error {
+	var cat models.Category
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&cat, id).Error; err != nil {
+		return err
+	}
+
+	// товары и подкатегории в корзине тоже ссылаются на категорию
+	var products int64
+	if err := tx.Unscoped().Model(&models.Product{}).Where("category_id = ?", cat.ID).Count(&products).Error; err != nil {
+		return err
+	}
+	if products > 0 {
+		return &errTrashConflict{
+			msg:    fmt.Sprintf("Невозможно удалить категорию '%s': к ней привязаны товары, в том числе в корзине", cat.Name),
+			extras: gin.H{"product_count": products},
+		}
+	}
+
+	var children int64
+	if err := tx.Unscoped().Model(&models.Category{}).Where("parent_id = ?", cat.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return &errTrashConflict{
+			msg:    fmt.Sprintf("Невозможно удалить категорию '%s': у неё есть подкатегории, в том числе в корзине", cat.Name),
+			extras: gin.H{"children": children},
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM promo_code_categories WHERE category_id = ?", cat.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("category_id = ?", cat.ID).Delete(&models.Attribute{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&cat).Error
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"bytes"
+	"clen_shop/config"
+	"clen_shop/dto"
+	"clen_shop/images"
+	"clen_shop/storage"
+	"clen_shop/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadTypes — допустимые типы (по содержимому файла, а не по заголовку) и их расширения.
+var uploadTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+func uploadMaxSize() int64 {
+	if s := os.Getenv("UPLOAD_MAX_MB"); s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+			return n << 20
+		}
+	}
+	return 5 << 20
+}
+
+// uploadKey — случайное имя в каталоге по месяцу загрузки.
+func uploadKey(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().Format("2006/01/") + hex.EncodeToString(b) + ext, nil
+}
+
+func AdminUpload(c *gin.Context) {
+	maxSize := uploadMaxSize()
+	// запас на заголовки multipart
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.RespondError(c, http.StatusRequestEntityTooLarge, "file too large")
+			return
+		}
+		utils.RespondError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if fh.Size > maxSize {
+		utils.RespondError(c, http.StatusRequestEntityTooLarge, "file too large",
+			gin.H{"max_size": maxSize})
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "cannot read file")
+		return
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		utils.RespondError(c, http.StatusBadRequest, "cannot read file")
+		return
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := uploadTypes[contentType]
+	if !ok {
+		utils.RespondError(c, http.StatusUnsupportedMediaType, "unsupported file type",
+			gin.H{"content_type": contentType})
+		return
+	}
+
+	key, err := uploadKey(ext)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "storage error")
+		return
+	}
+	body := io.MultiReader(bytes.NewReader(head), f)
+	if err := storage.Default.Put(c.Request.Context(), key, body, contentType); err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "storage error")
+		return
+	}
+
+	url := storage.Default.URL(key)
+	// варианты нарезаются в фоне и появятся в ответах чуть позже
+	images.Enqueue(url)
+
+	utils.RespondCreated(c, dto.UploadResponse{
+		URL:         url,
+		Key:         key,
+		ContentType: contentType,
+		Size:        fh.Size,
+	})
+}
+
+// AdminUploadsCleanup по умолчанию только возвращает отчёт о неиспользуемых
+// файлах старше grace-периода; удаляет их — с явным ?dry_run=false. Если ссылки
+// не сопоставились с путём хранилища, удаление не выполняется и в ответе dry_run=true.
+func AdminUploadsCleanup(c *gin.Context) {
+	dryRun := c.Query("dry_run") != "false"
+
+	report, err := images.Cleanup(c.Request.Context(), config.DB, storage.Default, dryRun)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "cleanup failed")
+		return
+	}
+
+	resp := dto.CleanupReportResponse{
+		DryRun:  report.DryRun,
+		Scanned: report.Scanned,
+		Recent:  report.Recent,
+		Bytes:   report.Bytes,
+		Files:   make([]dto.OrphanFileResponse, 0, len(report.Orphans)),
+
+		Unmapped: report.Unmapped,
+	}
+	if !report.DryRun {
+		resp.Removed = len(report.Orphans)
+	}
+	for _, o := range report.Orphans {
+		resp.Files = append(resp.Files, dto.OrphanFileResponse{
+			Key:     o.Key,
+			URL:     o.URL,
+			Size:    o.Size,
+			ModTime: o.ModTime,
+		})
+	}
+
+	utils.RespondOK(c, resp)
+}
@@ -0,0 +1,51 @@
+package dto
+
+type AttributeCreateRequest struct {
+	Code       string   `json:"code" validate:"required,min=1,max=50"`
+	Name       string   `json:"name" validate:"required,min=1,max=100"`
+	Type       string   `json:"type" validate:"required,oneof=number text enum bool"`
+	Unit       string   `json:"unit" validate:"max=20"`
+	Options    []string `json:"options" validate:"omitempty,dive,min=1,max=100"`
+	Min        *float64 `json:"min"`
+	Max        *float64 `json:"max"`
+	Required   bool     `json:"required"`
+	Filterable bool     `json:"filterable"`
+	SortOrder  int      `json:"sort_order"`
+}
+
+type AttributeUpdateRequest struct {
+	Name       *string           `json:"name" validate:"omitempty,min=1,max=100"`
+	Type       *string           `json:"type" validate:"omitempty,oneof=number text enum bool"`
+	Unit       *string           `json:"unit" validate:"omitempty,max=20"`
+	Options    *[]string         `json:"options" validate:"omitempty,dive,min=1,max=100"`
+	Min        Nullable[float64] `json:"min"`
+	Max        Nullable[float64] `json:"max"`
+	Required   *bool             `json:"required"`
+	Filterable *bool             `json:"filterable"`
+	SortOrder  *int              `json:"sort_order"`
+}
+
+type AttributeResponse struct {
+	ID         uint     `json:"id"`
+	CategoryID uint     `json:"category_id"`
+	Code       string   `json:"code"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Unit       string   `json:"unit"`
+	Options    []string `json:"options"`
+	Min        *float64 `json:"min"`
+	Max        *float64 `json:"max"`
+	Required   bool     `json:"required"`
+	Filterable bool     `json:"filterable"`
+	SortOrder  int      `json:"sort_order"`
+}
+
+// ProductAttributeDTO — значение атрибута в карточке товара.
+// Value — число, строка или bool в зависимости от Type.
+type ProductAttributeDTO struct {
+	Code  string      `json:"code"`
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Unit  string      `json:"unit"`
+	Value interface{} `json:"value"`
+}
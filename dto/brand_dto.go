@@ -0,0 +1,44 @@
+package dto
+
+type BrandCreateRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Slug        string `json:"slug" validate:"required,min=2,max=100"`
+	Description string `json:"description" validate:"max=5000"`
+	LogoURL     string `json:"logo_url" validate:"omitempty,url"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type BrandUpdateRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Slug        *string `json:"slug" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=5000"`
+	LogoURL     *string `json:"logo_url" validate:"omitempty,url"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+type BrandResponse struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Description  string `json:"description"`
+	LogoURL      string `json:"logo_url"`
+	IsActive     bool   `json:"is_active"`
+	ProductCount int64  `json:"product_count"`
+	// TrashedCount — товары бренда в корзине (только в админке): пока они
+	// есть, бренд нельзя удалить
+	TrashedCount *int64 `json:"trashed_count,omitempty"`
+}
+
+// BrandRef — бренд в карточке товара.
+type BrandRef struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Slug    string `json:"slug"`
+	LogoURL string `json:"logo_url"`
+}
+
+// BrandPageResponse — страница бренда: товары с фасетами и сам бренд.
+type BrandPageResponse struct {
+	ProductPage
+	Brand BrandResponse `json:"brand"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+type CartItemAddRequest struct {
+	ProductID uint   `json:"product_id" validate:"required"`
+	Taste     string `json:"taste" validate:"max=100"`
+	Quantity  int    `json:"quantity" validate:"required,min=1,max=100"`
+}
+
+type CartItemUpdateRequest struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=100"`
+}
+
+type CartItemResponse struct {
+	ID         uint   `json:"id"`
+	ProductID  uint   `json:"product_id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	ImageURL   string `json:"image_url"`
+	Taste      string `json:"taste"`
+	Quantity   int    `json:"quantity"`
+	Price      int64  `json:"price"`
+	PriceAtAdd int64  `json:"price_at_add"`
+	LineTotal  int64  `json:"line_total"`
+	Stock      int    `json:"stock"`
+	Available  bool   `json:"available"`
+	Problem    string `json:"problem,omitempty"`
+}
+
+type CartResponse struct {
+	ID          uint               `json:"id"`
+	Token       string             `json:"cart_token,omitempty"`
+	Items       []CartItemResponse `json:"items"`
+	TotalQty    int                `json:"total_qty"`
+	Total       int64              `json:"total"`
+	HasProblems bool               `json:"has_problems"`
+}
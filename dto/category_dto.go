@@ -1,13 +1,12 @@
 package dto
 
-import "clen_shop/models"
-
 type CategoryCreateRequest struct {
 	Name        string `json:"name" validate:"required,min=2,max=100"`
 	Slug        string `json:"slug" validate:"required,min=2,max=100"`
 	Description string `json:"description"`
 
-	ParentID *uint `json:"parent_id"`
+	ParentID  *uint `json:"parent_id"`
+	SortOrder *int  `json:"sort_order" validate:"omitempty,min=0"` // nil — в конец
 
 	ImageURL string `json:"image_url" validate:"omitempty,url"`
 }
@@ -16,16 +15,52 @@ type CategoryUpdateRequest struct {
 	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
 	Slug        *string `json:"slug" validate:"omitempty,min=2,max=100"`
 	Description *string `json:"description"`
-	ParentID    *uint   `json:"parent_id"`
 	ImageURL    *string `json:"image_url" validate:"omitempty,url"`
+
+	// null — сделать корневой, отсутствие поля — оставить родителя
+	ParentID  Nullable[uint] `json:"parent_id"`
+	SortOrder *int           `json:"sort_order" validate:"omitempty,min=0"`
+}
+
+// CategoryMoveRequest — перенос и/или перестановка категории.
+// Position — индекс среди новых соседей, nil — в конец.
+type CategoryMoveRequest struct {
+	ParentID Nullable[uint] `json:"parent_id"`
+	Position *int           `json:"position" validate:"omitempty,min=0"`
 }
 
 type CategoryResponse struct {
-	ID          uint             `json:"id"`
-	Name        string           `json:"name"`
-	Slug        string           `json:"slug"`
-	Description string           `json:"description"`
-	ImageURL    string           `json:"image_url"`
-	ParentID    *uint            `json:"parent_id"`
-	Products    []models.Product `json:"products"`
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	ParentID    *uint  `json:"parent_id"`
+	SortOrder   int    `json:"sort_order"`
+
+	// Breadcrumbs — путь от корня до категории (только в GetCategory)
+	Breadcrumbs []CategoryRef `json:"breadcrumbs,omitempty"`
+}
+
+// CategoryPageResponse — страница категории: сама категория, её прямые
+// подкатегории и товары (включая подкатегории) с фильтрами, как в каталоге.
+type CategoryPageResponse struct {
+	CategoryResponse
+	Subcategories []CategoryResponse `json:"subcategories"`
+	Products      ProductPage        `json:"products"`
+}
+
+type CategoryRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type CategoryTreeNode struct {
+	ID           uint               `json:"id"`
+	Name         string             `json:"name"`
+	Slug         string             `json:"slug"`
+	ImageURL     string             `json:"image_url"`
+	ProductCount int64              `json:"product_count"`
+	Children     []CategoryTreeNode `json:"children"`
 }
@@ -0,0 +1,27 @@
+package dto
+
+// FacetValue — значение фасета и число товаров с ним при остальных фильтрах.
+// Value передаётся обратно в соответствующий параметр запроса.
+type FacetValue struct {
+	ID    uint   `json:"id,omitempty"`
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+type PriceBucket struct {
+	Value string `json:"value"` // для price_range
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int64  `json:"count"`
+}
+
+type ProductFacets struct {
+	Categories []FacetValue  `json:"categories"`
+	Brands     []FacetValue  `json:"brands"`
+	Tastes     []FacetValue  `json:"tastes"`
+	InStock    int64         `json:"in_stock"`
+	Prices     []PriceBucket `json:"prices"`
+	PriceMin   int64         `json:"price_min"`
+	PriceMax   int64         `json:"price_max"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "encoding/json"
+
+// Nullable различает «поле не передано» (Set=false) и «передан null»
+// (Set=true, Value=nil) — обычный указатель в PATCH-подобных запросах
+// этого не умеет.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
@@ -0,0 +1,63 @@
+package dto
+
+import "time"
+
+type CheckoutRequest struct {
+	CustomerName string `json:"customer_name" validate:"required,min=2,max=100"`
+	Phone        string `json:"phone" validate:"required,min=5,max=30"`
+	Address      string `json:"address" validate:"max=500"`
+	Comment      string `json:"comment" validate:"max=1000"`
+	PromoCode    string `json:"promo_code" validate:"max=50"`
+}
+
+// OrderLineError — почему конкретную позицию корзины нельзя заказать.
+type OrderLineError struct {
+	ProductID uint   `json:"product_id"`
+	Name      string `json:"name"`
+	Taste     string `json:"taste"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+	Error     string `json:"error"`
+}
+
+type OrderItemResponse struct {
+	ID          uint   `json:"id"`
+	ProductID   uint   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	ProductSlug string `json:"product_slug"`
+	Taste       string `json:"taste"`
+	Price       int64  `json:"price"`
+	Quantity    int    `json:"quantity"`
+	LineTotal   int64  `json:"line_total"`
+}
+
+type OrderResponse struct {
+	ID           uint                `json:"id"`
+	UserID       *uint               `json:"user_id"`
+	Status       string              `json:"status"`
+	CustomerName string              `json:"customer_name"`
+	Phone        string              `json:"phone"`
+	Address      string              `json:"address"`
+	Comment      string              `json:"comment"`
+	Subtotal     int64               `json:"subtotal"`
+	PromoCode    string              `json:"promo_code"`
+	Discount     int64               `json:"discount"`
+	Total        int64               `json:"total"`
+	Items        []OrderItemResponse `json:"items"`
+	History      []OrderStatusChange `json:"history,omitempty"`
+	NextStatuses []string            `json:"next_statuses"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+type OrderStatusChange struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *uint     `json:"changed_by"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type OrderStatusUpdateRequest struct {
+	Status  string `json:"status" validate:"required"`
+	Comment string `json:"comment" validate:"max=500"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type ProductCreateRequest struct {
 	Name        string `json:"name" validate:"required,min=2,max=100"`
 	Slug        string `json:"slug" validate:"required,min=2,max=100"`
@@ -8,14 +10,22 @@ type ProductCreateRequest struct {
 	Stock       int    `json:"stock" validate:"min=0"`
 	IsActive    *bool  `json:"is_active"`
 	CategoryID  uint   `json:"category_id" validate:"required"`
+	BrandID     *uint  `json:"brand_id"`
+
+	CompareAtPrice *int64     `json:"compare_at_price" validate:"omitempty,min=1"`
+	SalePrice      *int64     `json:"sale_price" validate:"omitempty,min=1"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at"`
 
 	Tastes []string `json:"tastes" validate:"omitempty,dive,min=1,max=100"`
 
-	Images []struct {
-		URL       string `json:"url" validate:"required,url"`
-		IsPrimary bool   `json:"is_primary"`
-		SortOrder int    `json:"sort_order" validate:"min=0"`
-	} `json:"images" validate:"dive"`
+	// Variants — полноценные варианты; если переданы, поле tastes игнорируется
+	Variants []ProductVariantInput `json:"variants" validate:"omitempty,dive"`
+
+	// Attributes — значения атрибутов категории по коду: {"weight_g": 908, "form": "порошок"}
+	Attributes map[string]interface{} `json:"attributes"`
+
+	Images []ProductImageInput `json:"images" validate:"dive"`
 }
 
 type ProductUpdateRequest struct {
@@ -27,26 +37,90 @@ type ProductUpdateRequest struct {
 	IsActive    *bool   `json:"is_active"`
 	CategoryID  *uint   `json:"category_id"`
 
+	// null снимает значение, отсутствие поля — оставляет как есть
+	BrandID        Nullable[uint]      `json:"brand_id"`
+	CompareAtPrice Nullable[int64]     `json:"compare_at_price"`
+	SalePrice      Nullable[int64]     `json:"sale_price"`
+	SaleStartsAt   Nullable[time.Time] `json:"sale_starts_at"`
+	SaleEndsAt     Nullable[time.Time] `json:"sale_ends_at"`
+
 	Tastes *[]string `json:"tastes" validate:"omitempty,dive,min=1,max=100"`
 
-	Images []struct {
-		URL       string `json:"url" validate:"required,url"`
-		IsPrimary bool   `json:"is_primary"`
-		SortOrder int    `json:"sort_order" validate:"min=0"`
-	} `json:"images" validate:"omitempty,dive"`
+	Variants *[]ProductVariantInput `json:"variants" validate:"omitempty,dive"`
+
+	// Attributes заменяет все значения атрибутов; null у ключа удаляет значение
+	Attributes *map[string]interface{} `json:"attributes"`
+
+	// Images синхронизирует картинки (см. ProductImageInput); пустой список удаляет все.
+	// Для точечных правок есть отдельные эндпоинты /admin/products/:id/images
+	Images *[]ProductImageInput `json:"images" validate:"omitempty,dive"`
 }
 
 type ProductResponse struct {
-	ID          uint              `json:"id"`
-	Name        string            `json:"name"`
-	Slug        string            `json:"slug"`
-	Description string            `json:"description"`
-	Price       int64             `json:"price"`
-	Stock       int               `json:"stock"`
-	IsActive    bool              `json:"is_active"`
-	CategoryID  uint              `json:"category_id"`
-	Images      []ProductImageDTO `json:"images"`
-	Tastes      []string          `json:"tastes"`
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+	Stock       int    `json:"stock"`
+	IsActive    bool   `json:"is_active"`
+	CategoryID  uint   `json:"category_id"`
+
+	BrandID *uint     `json:"brand_id"`
+	Brand   *BrandRef `json:"brand"`
+
+	// EffectivePrice — цена, по которой товар продаётся прямо сейчас;
+	// OriginalPrice — зачёркнутая цена (равна EffectivePrice, если скидки нет)
+	EffectivePrice  int64      `json:"effective_price"`
+	OriginalPrice   int64      `json:"original_price"`
+	DiscountPercent int        `json:"discount_percent"`
+	CompareAtPrice  *int64     `json:"compare_at_price"`
+	SalePrice       *int64     `json:"sale_price"`
+	SaleStartsAt    *time.Time `json:"sale_starts_at"`
+	SaleEndsAt      *time.Time `json:"sale_ends_at"`
+
+	// LowestPrice30d — минимальная цена за 30 дней до начала текущей распродажи
+	// (без распродажи — за последние 30 дней; только в карточке товара)
+	LowestPrice30d *int64 `json:"lowest_price_30d,omitempty"`
+
+	// Breadcrumbs — путь по категориям от корня (только в карточке товара)
+	Breadcrumbs []CategoryRef `json:"breadcrumbs,omitempty"`
+
+	Rating      float64 `json:"rating"`
+	RatingCount int     `json:"rating_count"`
+
+	// PricePerServing — цена за порцию, если у товара задан атрибут servings
+	PricePerServing *int64                `json:"price_per_serving"`
+	Attributes      []ProductAttributeDTO `json:"attributes"`
+
+	Images   []ProductImageDTO   `json:"images"`
+	Tastes   []string            `json:"tastes"`
+	Variants []ProductVariantDTO `json:"variants"`
+}
+
+// ProductVariantInput — вариант в запросе. Существующий вариант ищется по id,
+// а если id нет — по названию; так варианты сохраняют свои ID между правками.
+type ProductVariantInput struct {
+	ID        *uint  `json:"id"`
+	Name      string `json:"name" validate:"required,min=1,max=100"`
+	SKU       string `json:"sku" validate:"max=64"`
+	Barcode   string `json:"barcode" validate:"max=64"`
+	Stock     int    `json:"stock" validate:"min=0"`
+	Price     *int64 `json:"price" validate:"omitempty,min=1"`
+	IsActive  *bool  `json:"is_active"`
+	SortOrder int    `json:"sort_order" validate:"min=0"`
+}
+
+type ProductVariantDTO struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	Barcode   string `json:"barcode"`
+	Stock     int    `json:"stock"`
+	Price     int64  `json:"price"`
+	HasPrice  bool   `json:"has_own_price"`
+	IsActive  bool   `json:"is_active"`
+	SortOrder int    `json:"sort_order"`
 }
 
 type ProductImageDTO struct {
@@ -54,4 +128,111 @@ type ProductImageDTO struct {
 	URL       string `json:"url"`
 	IsPrimary bool   `json:"is_primary"`
 	SortOrder int    `json:"sort_order"`
+
+	// Variants — уменьшенные копии по размерам (thumb, card, full);
+	// пусто, пока фоновый воркер не обработал файл
+	Variants map[string]ImageVariantDTO `json:"variants"`
+	// Srcset и SrcsetWebP — готовые значения для <img srcset> и <source type="image/webp">
+	Srcset     string `json:"srcset,omitempty"`
+	SrcsetWebP string `json:"srcset_webp,omitempty"`
+}
+
+// ProductImageInput — картинка в запросе. Существующая ищется по id, а если id
+// нет — по url; так картинки сохраняют свои ID между правками.
+type ProductImageInput struct {
+	ID        *uint  `json:"id"`
+	URL       string `json:"url" validate:"required,url"`
+	IsPrimary bool   `json:"is_primary"`
+	SortOrder int    `json:"sort_order" validate:"min=0"`
+}
+
+type ProductImageCreateRequest struct {
+	URL       string `json:"url" validate:"required,url,max=500"`
+	IsPrimary bool   `json:"is_primary"`
+	// Position — место в галерее с нуля; по умолчанию картинка добавляется в конец
+	Position *int `json:"position" validate:"omitempty,min=0"`
+}
+
+// ProductImageOrderRequest — новый порядок: все ID картинок товара по разу.
+type ProductImageOrderRequest struct {
+	IDs []uint `json:"ids" validate:"required,min=1"`
+}
+
+type ImageVariantDTO struct {
+	URL    string `json:"url"`
+	WebP   string `json:"webp"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type StockMovementRequest struct {
+	VariantID *uint  `json:"variant_id"`
+	Kind      string `json:"kind" validate:"required,oneof=receipt return adjustment write_off"`
+	// для adjustment — со знаком, для остальных видов — положительное количество
+	Quantity  int    `json:"quantity" validate:"required,ne=0"`
+	Reason    string `json:"reason" validate:"required,min=2,max=500"`
+	Reference string `json:"reference" validate:"max=100"`
+}
+
+type StockMovementResponse struct {
+	ID        uint      `json:"id"`
+	ProductID uint      `json:"product_id"`
+	VariantID *uint     `json:"variant_id"`
+	Kind      string    `json:"kind"`
+	Delta     int       `json:"delta"`
+	Balance   int       `json:"balance"`
+	Reason    string    `json:"reason"`
+	ActorID   *uint     `json:"actor_id"`
+	Reference string    `json:"reference"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type StockBatchCreateRequest struct {
+	VariantID *uint      `json:"variant_id"`
+	LotNumber string     `json:"lot_number" validate:"required,min=1,max=64"`
+	Quantity  int        `json:"quantity" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Reason    string     `json:"reason" validate:"max=500"`
+}
+
+type StockBatchWriteOffRequest struct {
+	// если не указано — списывается весь остаток партии
+	Quantity *int   `json:"quantity" validate:"omitempty,min=1"`
+	Reason   string `json:"reason" validate:"required,min=2,max=500"`
+}
+
+type StockBatchResponse struct {
+	ID              uint       `json:"id"`
+	ProductID       uint       `json:"product_id"`
+	ProductName     string     `json:"product_name,omitempty"`
+	VariantID       *uint      `json:"variant_id"`
+	VariantName     string     `json:"variant_name,omitempty"`
+	LotNumber       string     `json:"lot_number"`
+	InitialQuantity int        `json:"initial_quantity"`
+	Quantity        int        `json:"quantity"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	DaysLeft        *int       `json:"days_left"`
+	ReceivedAt      time.Time  `json:"received_at"`
+}
+
+type PriceChangeResponse struct {
+	ID        uint      `json:"id"`
+	OldPrice  int64     `json:"old_price"`
+	NewPrice  int64     `json:"new_price"`
+	ChangedBy *uint     `json:"changed_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// распродажа после правки
+	NewSalePrice    *int64     `json:"new_sale_price"`
+	NewSaleStartsAt *time.Time `json:"new_sale_starts_at"`
+	NewSaleEndsAt   *time.Time `json:"new_sale_ends_at"`
+}
+
+// ProductPage — страница витрины: товары, общее число и фасеты.
+type ProductPage struct {
+	Page   int               `json:"page"`
+	Limit  int               `json:"limit"`
+	Total  int64             `json:"total"`
+	Items  []ProductResponse `json:"items"`
+	Facets ProductFacets     `json:"facets"`
 }
@@ -0,0 +1,65 @@
+package dto
+
+import "time"
+
+type PromoCodeCreateRequest struct {
+	Code          string     `json:"code" validate:"required,min=3,max=50,alphanum"`
+	Description   string     `json:"description" validate:"max=500"`
+	Kind          string     `json:"kind" validate:"required,oneof=percent fixed"`
+	Value         int64      `json:"value" validate:"required,min=1"`
+	MaxDiscount   *int64     `json:"max_discount" validate:"omitempty,min=1"`
+	MinOrderTotal int64      `json:"min_order_total" validate:"min=0"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	UsageLimit    *int       `json:"usage_limit" validate:"omitempty,min=1"`
+	PerUserLimit  *int       `json:"per_user_limit" validate:"omitempty,min=1"`
+	IsActive      *bool      `json:"is_active"`
+	CategoryIDs   []uint     `json:"category_ids"`
+	ProductIDs    []uint     `json:"product_ids"`
+}
+
+type PromoCodeUpdateRequest struct {
+	Description   *string `json:"description" validate:"omitempty,max=500"`
+	Kind          *string `json:"kind" validate:"omitempty,oneof=percent fixed"`
+	Value         *int64  `json:"value" validate:"omitempty,min=1"`
+	MaxDiscount   *int64  `json:"max_discount" validate:"omitempty,min=0"` // 0 — снять потолок
+	MinOrderTotal *int64  `json:"min_order_total" validate:"omitempty,min=0"`
+	UsageLimit    *int    `json:"usage_limit" validate:"omitempty,min=0"`    // 0 — без лимита
+	PerUserLimit  *int    `json:"per_user_limit" validate:"omitempty,min=0"` // 0 — без лимита
+	IsActive      *bool   `json:"is_active"`
+	CategoryIDs   *[]uint `json:"category_ids"`
+	ProductIDs    *[]uint `json:"product_ids"`
+
+	// null снимает границу срока действия, отсутствие поля — оставляет как есть
+	StartsAt Nullable[time.Time] `json:"starts_at"`
+	EndsAt   Nullable[time.Time] `json:"ends_at"`
+}
+
+type PromoCodeResponse struct {
+	ID            uint       `json:"id"`
+	Code          string     `json:"code"`
+	Description   string     `json:"description"`
+	Kind          string     `json:"kind"`
+	Value         int64      `json:"value"`
+	MaxDiscount   *int64     `json:"max_discount"`
+	MinOrderTotal int64      `json:"min_order_total"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	UsageLimit    *int       `json:"usage_limit"`
+	PerUserLimit  *int       `json:"per_user_limit"`
+	UsedCount     int        `json:"used_count"`
+	IsActive      bool       `json:"is_active"`
+	CategoryIDs   []uint     `json:"category_ids"`
+	ProductIDs    []uint     `json:"product_ids"`
+}
+
+type PromoApplyRequest struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
+
+type PromoApplyResponse struct {
+	Code     string `json:"code"`
+	Subtotal int64  `json:"subtotal"`
+	Discount int64  `json:"discount"`
+	Total    int64  `json:"total"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+type ReviewCreateRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Text   string `json:"text" validate:"max=3000"`
+	Taste  string `json:"taste" validate:"max=100"`
+}
+
+type ReviewModerateRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected"`
+	Note   string `json:"note" validate:"max=500"`
+}
+
+type ReviewResponse struct {
+	ID          uint       `json:"id"`
+	ProductID   uint       `json:"product_id"`
+	ProductName string     `json:"product_name,omitempty"`
+	AuthorName  string     `json:"author_name"`
+	Rating      int        `json:"rating"`
+	Text        string     `json:"text"`
+	Taste       string     `json:"taste"`
+	Status      string     `json:"status,omitempty"`
+	Note        string     `json:"moderation_note,omitempty"`
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
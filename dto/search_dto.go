@@ -0,0 +1,27 @@
+package dto
+
+type SuggestProduct struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Price    int64  `json:"price"`
+	ImageURL string `json:"image_url"`
+}
+
+type SuggestCategory struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type SuggestTaste struct {
+	Name         string `json:"name"`
+	ProductCount int    `json:"product_count"`
+}
+
+type SuggestResponse struct {
+	Query      string            `json:"query"`
+	Products   []SuggestProduct  `json:"products"`
+	Categories []SuggestCategory `json:"categories"`
+	Tastes     []SuggestTaste    `json:"tastes"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// TrashItem — удалённый товар или категория в корзине.
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// SlugTaken — slug уже занят другой записью: восстановление вернёт 409, пока он не освободится
+	SlugTaken bool `json:"slug_taken"`
+}
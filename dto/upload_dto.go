@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type UploadResponse struct {
+	// URL подходит для полей image_url категорий и images[].url товаров
+	URL         string `json:"url"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+type OrphanFileResponse struct {
+	Key     string    `json:"key"`
+	URL     string    `json:"url"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// CleanupReportResponse — отчёт сборщика неиспользуемых файлов;
+// при dry_run файлы только перечислены, но не удалены.
+type CleanupReportResponse struct {
+	DryRun  bool                 `json:"dry_run"`
+	Scanned int                  `json:"scanned"`
+	Recent  int                  `json:"recent"`
+	Removed int                  `json:"removed"`
+	Bytes   int64                `json:"bytes"`
+	Files   []OrphanFileResponse `json:"files"`
+
+	// Unmapped — ссылки на файлы хранилища под другим путём; при них ничего не удаляется
+	Unmapped int `json:"unmapped"`
+}
@@ -0,0 +1,205 @@
+package images
+
+import (
+	"clen_shop/models"
+	"clen_shop/storage"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Orphan — файл в хранилище, на который ничего не ссылается.
+type Orphan struct {
+	Key     string
+	URL     string
+	Size    int64
+	ModTime time.Time
+}
+
+// CleanupReport — итог прохода сборщика. В режиме DryRun файлы только перечисляются.
+type CleanupReport struct {
+	DryRun  bool
+	Scanned int
+	// Recent — неиспользуемые файлы моложе grace: их, скорее всего, ещё не успели привязать
+	Recent  int
+	Orphans []Orphan
+	Bytes   int64
+	// Unmapped — ссылки на файлы хранилища под другим путём (сменился
+	// UPLOAD_BASE_URL); если они есть, удаление не выполняется
+	Unmapped int
+}
+
+func cleanupGrace() time.Duration {
+	if s := os.Getenv("UPLOAD_GC_GRACE_H"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			return time.Duration(n) * time.Hour
+		}
+	}
+	return 24 * time.Hour
+}
+
+func cleanupInterval() time.Duration {
+	if s := os.Getenv("UPLOAD_GC_INTERVAL_H"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			return time.Duration(n) * time.Hour
+		}
+	}
+	return 24 * time.Hour
+}
+
+// StartCleanup периодически ищет неиспользуемые файлы. По умолчанию только
+// пишет отчёт в лог; удаляет — при UPLOAD_GC_DELETE=true.
+// UPLOAD_GC_INTERVAL_H=0 выключает сборщик.
+func StartCleanup(db *gorm.DB, store storage.Storage) {
+	interval := cleanupInterval()
+	if interval == 0 {
+		return
+	}
+	dryRun := os.Getenv("UPLOAD_GC_DELETE") != "true"
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for range t.C {
+			r, err := Cleanup(context.Background(), db, store, dryRun)
+			if err != nil {
+				log.Printf("images: cleanup: %v", err)
+				continue
+			}
+			if len(r.Orphans) > 0 {
+				log.Printf("images: cleanup: %d orphaned files, %d bytes (dry run: %v)", len(r.Orphans), r.Bytes, r.DryRun)
+			}
+		}
+	}()
+}
+
+// Cleanup находит файлы, на которые не ссылаются картинки товаров, категории
+// и бренды (включая удалённые в корзину), и удаляет те, что старше grace,
+// вместе с их уменьшенными копиями.
+func Cleanup(ctx context.Context, db *gorm.DB, store storage.Storage, dryRun bool) (CleanupReport, error) {
+	report := CleanupReport{DryRun: dryRun, Orphans: []Orphan{}}
+
+	// список файлов берём до ссылок: файл, загруженный и привязанный
+	// между двумя запросами, окажется моложе grace и не пострадает
+	objs, err := store.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Scanned = len(objs)
+
+	stored := make(map[string]bool, len(objs))
+	for _, o := range objs {
+		stored[o.Key] = true
+	}
+
+	used, unmapped, err := referencedKeys(db, store, stored)
+	if err != nil {
+		return report, err
+	}
+
+	// ссылки на наши файлы, которые не сопоставились с ключами, означают, что
+	// путь хранилища сменился: удалять в такой ситуации нельзя ничего
+	report.Unmapped = unmapped
+	if unmapped > 0 && !dryRun {
+		log.Printf("images: cleanup: %d references do not match the storage path, falling back to dry run", unmapped)
+		report.DryRun = true
+	}
+
+	cutoff := time.Now().Add(-cleanupGrace())
+	for _, o := range objs {
+		if used[o.Key] {
+			continue
+		}
+		if o.ModTime.After(cutoff) {
+			report.Recent++
+			continue
+		}
+		report.Orphans = append(report.Orphans, Orphan{
+			Key:     o.Key,
+			URL:     store.URL(o.Key),
+			Size:    o.Size,
+			ModTime: o.ModTime,
+		})
+		report.Bytes += o.Size
+	}
+
+	if report.DryRun {
+		return report, nil
+	}
+
+	for _, o := range report.Orphans {
+		// записи о вариантах удаляем раньше файлов: без записи файл
+		// варианта сам станет сиротой и уйдёт при следующем проходе
+		if err := db.Where("source_url = ? OR url = ?", o.URL, o.URL).
+			Delete(&models.ImageVariant{}).Error; err != nil {
+			return report, err
+		}
+		if err := store.Delete(ctx, o.Key); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// referencedKeys — ключи всех файлов, на которые есть ссылки, включая варианты
+// используемых картинок. unmapped — число ссылок, которые не сопоставились
+// с ключом, но оканчиваются ключом существующего файла.
+func referencedKeys(db *gorm.DB, store storage.Storage, stored map[string]bool) (used map[string]bool, unmapped int, err error) {
+	var urls []string
+	sources := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.ProductImage{}, "url"},
+		{&models.Category{}, "image_url"},
+		{&models.Brand{}, "logo_url"},
+	}
+	for _, s := range sources {
+		var part []string
+		if err := db.Unscoped().Model(s.model).
+			Where(s.column+" <> ''").
+			Distinct().Pluck(s.column, &part).Error; err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, part...)
+	}
+
+	used = make(map[string]bool, len(urls))
+	for _, u := range urls {
+		if key, ok := store.Key(u); ok {
+			used[key] = true
+		} else if endsWithKey(u, stored) {
+			unmapped++
+		}
+	}
+
+	var variants []models.ImageVariant
+	if err := db.Select("source_url", "url").Find(&variants).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, v := range variants {
+		src, ok := store.Key(v.SourceURL)
+		if !ok || !used[src] {
+			continue
+		}
+		if key, ok := store.Key(v.URL); ok {
+			used[key] = true
+		}
+	}
+
+	return used, unmapped, nil
+}
+
+// endsWithKey — оканчивается ли URL ключом одного из файлов хранилища.
+func endsWithKey(u string, stored map[string]bool) bool {
+	for i := range len(u) {
+		if u[i] == '/' && stored[u[i+1:]] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package images
+
+import (
+	"bytes"
+	"clen_shop/models"
+	"clen_shop/storage"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	_ "image/gif"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Size — ширина варианта; высота считается по пропорциям исходника.
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes — нарезаемые варианты от меньшего к большему.
+var Sizes = []Size{
+	{models.ImageSizeThumb, 200},
+	{models.ImageSizeCard, 600},
+	{models.ImageSizeFull, 1600},
+}
+
+// maxPixels защищает от «бомб» — маленьких файлов с огромным разрешением.
+const maxPixels = 50_000_000
+
+const jpegQuality = 82
+
+var queue = make(chan string, 256)
+
+// Enqueue ставит URL загруженного файла в очередь на нарезку. Если очередь
+// переполнена, файл будет обработан при следующем запуске (см. backfill).
+func Enqueue(url string) {
+	select {
+	case queue <- url:
+	default:
+		log.Printf("images: queue is full, %s postponed", url)
+	}
+}
+
+// Start запускает фоновый воркер и досчитывает варианты для картинок товаров,
+// у которых их ещё нет.
+func Start(db *gorm.DB, store storage.Storage) {
+	go func() {
+		for url := range queue {
+			if err := Process(context.Background(), db, store, url); err != nil {
+				log.Printf("images: %s: %v", url, err)
+			}
+		}
+	}()
+
+	go func() {
+		var urls []string
+		if err := db.Model(&models.ProductImage{}).
+			Where("NOT EXISTS (SELECT 1 FROM image_variants v WHERE v.source_url = product_images.url)").
+			Distinct().Pluck("url", &urls).Error; err != nil {
+			log.Printf("images: backfill: %v", err)
+			return
+		}
+		for _, u := range urls {
+			if _, ok := store.Key(u); ok {
+				queue <- u
+			}
+		}
+	}()
+}
+
+// Process нарезает варианты для одного файла. Внешние URL и уже обработанные
+// файлы пропускаются.
+func Process(ctx context.Context, db *gorm.DB, store storage.Storage, url string) error {
+	key, ok := store.Key(url)
+	if !ok {
+		return nil
+	}
+
+	// WebP есть не у всех размеров, поэтому готовность считаем по JPEG/PNG
+	var done int64
+	if err := db.Model(&models.ImageVariant{}).
+		Where("source_url = ? AND format <> ?", url, models.ImageFormatWebP).
+		Count(&done).Error; err != nil {
+		return err
+	}
+	if done >= int64(len(Sizes)) {
+		return nil
+	}
+
+	src, err := decode(ctx, store, key)
+	if err != nil {
+		return err
+	}
+
+	// прозрачность сохраняем в PNG, остальное — в JPEG
+	format := models.ImageFormatJPEG
+	if o, ok := src.(interface{ Opaque() bool }); ok && !o.Opaque() {
+		format = models.ImageFormatPNG
+	}
+
+	base := "variants/" + strings.TrimSuffix(key, path.Ext(key))
+	b := src.Bounds()
+
+	for _, s := range Sizes {
+		w := min(s.Width, b.Dx())
+		h := max(1, b.Dy()*w/b.Dx())
+
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+		var raster, webp bytes.Buffer
+		ext, contentType, err := encode(&raster, dst, format)
+		if err != nil {
+			return err
+		}
+		rasterSize := raster.Len() // Put вычитает буфер
+		if err := saveVariant(ctx, db, store, url, base, s.Name, format, ext, contentType, &raster, w, h); err != nil {
+			return err
+		}
+
+		// кодировщик WebP без потерь: для фотографий он часто крупнее JPEG,
+		// а браузер выбирает WebP — такой вариант только навредит
+		ext, contentType, err = encode(&webp, dst, models.ImageFormatWebP)
+		if err != nil {
+			return err
+		}
+		if webp.Len() >= rasterSize {
+			if err := db.Where("source_url = ? AND size = ? AND format = ?", url, s.Name, models.ImageFormatWebP).
+				Delete(&models.ImageVariant{}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := saveVariant(ctx, db, store, url, base, s.Name, models.ImageFormatWebP, ext, contentType, &webp, w, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveVariant(ctx context.Context, db *gorm.DB, store storage.Storage, url, base, size, format, ext, contentType string, data io.Reader, w, h int) error {
+	vkey := fmt.Sprintf("%s_%s%s", base, size, ext)
+	if err := store.Put(ctx, vkey, data, contentType); err != nil {
+		return err
+	}
+
+	v := models.ImageVariant{
+		SourceURL: url,
+		Size:      size,
+		Format:    format,
+		URL:       store.URL(vkey),
+		Width:     w,
+		Height:    h,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_url"}, {Name: "size"}, {Name: "format"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "width", "height"}),
+	}).Create(&v).Error
+}
+
+func decode(ctx context.Context, store storage.Storage, key string) (image.Image, error) {
+	rc, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("unsupported dimensions %dx%d", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// encode возвращает расширение и content-type закодированного варианта.
+// WebP кодируется без потерь: чистый Go-кодировщик умеет только VP8L,
+// поэтому Process сохраняет его, только если он меньше JPEG/PNG.
+func encode(w io.Writer, img image.Image, format string) (string, string, error) {
+	switch format {
+	case models.ImageFormatJPEG:
+		return ".jpg", "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case models.ImageFormatPNG:
+		return ".png", "image/png", png.Encode(w, img)
+	case models.ImageFormatWebP:
+		return ".webp", "image/webp", nativewebp.Encode(w, img, nil)
+	default:
+		return "", "", fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -2,14 +2,72 @@ package main
 
 import (
 	"clen_shop/config"
+	"clen_shop/images"
 	"clen_shop/models"
 	"clen_shop/routes"
+	"clen_shop/search"
+	"clen_shop/storage"
+	"log"
 )
 
 func main() {
 
 	config.ConnectDB()
-	config.DB.AutoMigrate(&models.Category{}, &models.Product{}, &models.ProductImage{}, &models.RefreshToken{}, &models.User{}, &models.ProductTaste{})
+
+	// у вариантов, заведённых до появления is_active, колонку добавляем
+	// с DEFAULT true; в модели default нет, иначе GORM подменял бы им false
+	if config.DB.Migrator().HasTable(&models.ProductTaste{}) &&
+		!config.DB.Migrator().HasColumn(&models.ProductTaste{}, "is_active") {
+		if err := config.DB.Exec("ALTER TABLE product_tastes ADD COLUMN is_active boolean NOT NULL DEFAULT true").Error; err != nil {
+			log.Fatal("add product_tastes.is_active: ", err)
+		}
+	}
+
+	// перед уникальным индексом основной картинки снимаем лишние отметки,
+	// оставшиеся от гонок: основной остаётся первая
+	if config.DB.Migrator().HasTable(&models.ProductImage{}) &&
+		!config.DB.Migrator().HasIndex(&models.ProductImage{}, "idx_product_images_primary") {
+		if err := config.DB.Exec(`UPDATE product_images pi SET is_primary = false
+			WHERE pi.is_primary AND pi.deleted_at IS NULL AND EXISTS (
+				SELECT 1 FROM product_images o
+				WHERE o.product_id = pi.product_id AND o.is_primary AND o.deleted_at IS NULL AND o.id < pi.id
+			)`).Error; err != nil {
+			log.Fatal("dedupe primary images: ", err)
+		}
+	}
+
+	config.DB.AutoMigrate(&models.Category{}, &models.Brand{}, &models.Product{}, &models.ProductImage{}, &models.RefreshToken{}, &models.User{}, &models.ProductTaste{},
+		&models.Cart{}, &models.CartItem{}, &models.Order{}, &models.OrderItem{}, &models.OrderStatusHistory{},
+		&models.StockMovement{}, &models.StockBatch{}, &models.StockBatchAllocation{},
+		&models.PromoCode{}, &models.PromoRedemption{}, &models.PriceChange{},
+		&models.Review{}, &models.Attribute{}, &models.ProductAttributeValue{}, &models.ImageVariant{})
+
+	// slug и код промокода уникальны только среди неудалённых записей, чтобы
+	// удалённые не занимали их; прежние полные индексы убираем
+	for _, idx := range []struct {
+		model interface{}
+		name  string
+	}{
+		{&models.Product{}, "idx_products_slug"},
+		{&models.Category{}, "idx_categories_slug"},
+		{&models.PromoCode{}, "idx_promo_codes_code"},
+	} {
+		if config.DB.Migrator().HasIndex(idx.model, idx.name) {
+			if err := config.DB.Migrator().DropIndex(idx.model, idx.name); err != nil {
+				log.Fatal("drop index: ", err)
+			}
+		}
+	}
+
+	if err := search.Setup(config.DB); err != nil {
+		log.Fatal("search setup: ", err)
+	}
+
+	if err := storage.Setup(); err != nil {
+		log.Fatal("storage setup: ", err)
+	}
+	images.Start(config.DB, storage.Default)
+	images.StartCleanup(config.DB, storage.Default)
 
 	r := routes.SetupRoutes()
 
@@ -0,0 +1,51 @@
+package models
+
+import "gorm.io/gorm"
+
+type AttributeType string
+
+const (
+	AttrNumber AttributeType = "number"
+	AttrText   AttributeType = "text"
+	AttrEnum   AttributeType = "enum"
+	AttrBool   AttributeType = "bool"
+)
+
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttrNumber, AttrText, AttrEnum, AttrBool:
+		return true
+	}
+	return false
+}
+
+// AttrServings — код атрибута «порций в упаковке»; по нему считается цена за порцию.
+const AttrServings = "servings"
+
+// Attribute — типизированная характеристика товаров категории
+// (вес в граммах, порций в упаковке, белка на порцию, форма выпуска…).
+type Attribute struct {
+	gorm.Model
+	CategoryID uint          `gorm:"not null;uniqueIndex:idx_attributes_category_code"`
+	Code       string        `gorm:"size:50;not null;uniqueIndex:idx_attributes_category_code"`
+	Name       string        `gorm:"size:100;not null"`
+	Type       AttributeType `gorm:"size:10;not null"`
+	Unit       string        `gorm:"size:20"`
+	Options    []string      `gorm:"serializer:json;type:text"` // допустимые значения для enum
+	Min        *float64      // границы для number
+	Max        *float64
+	Required   bool `gorm:"not null;default:false"`
+	Filterable bool `gorm:"not null;default:false"`
+	SortOrder  int  `gorm:"not null;default:0"`
+}
+
+// ProductAttributeValue — значение атрибута у товара. Числа хранятся
+// в NumberValue, остальное (включая bool как "true"/"false") — в TextValue.
+type ProductAttributeValue struct {
+	ID          uint      `gorm:"primaryKey"`
+	ProductID   uint      `gorm:"not null;uniqueIndex:idx_product_attribute"`
+	AttributeID uint      `gorm:"not null;uniqueIndex:idx_product_attribute;index"`
+	Attribute   Attribute `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	NumberValue *float64  `gorm:"index"`
+	TextValue   string    `gorm:"size:255;index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+type Brand struct {
+	gorm.Model
+	Name        string `gorm:"size:100;not null"`
+	Slug        string `gorm:"size:100;uniqueIndex;not null"`
+	Description string `gorm:"type:text"`
+	LogoURL     string `gorm:"size:500"`
+	IsActive    bool   `gorm:"not null"` // без default: GORM подставил бы его вместо false
+}
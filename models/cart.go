@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/gorm"
+
+// Cart принадлежит либо пользователю (UserID), либо гостю (Token).
+type Cart struct {
+	gorm.Model
+	UserID *uint      `gorm:"uniqueIndex"`
+	Token  *string    `gorm:"size:64;uniqueIndex"`
+	Items  []CartItem `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type CartItem struct {
+	gorm.Model
+	CartID    uint    `gorm:"not null;uniqueIndex:idx_cart_line"`
+	ProductID uint    `gorm:"not null;index;uniqueIndex:idx_cart_line"`
+	Product   Product `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Taste     string  `gorm:"size:100;not null;default:'';uniqueIndex:idx_cart_line"`
+	Quantity  int     `gorm:"not null"`
+
+	// цена на момент добавления — чтобы показать покупателю, что она изменилась
+	PriceAtAdd int64 `gorm:"not null"`
+}
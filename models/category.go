@@ -5,13 +5,14 @@ import "gorm.io/gorm"
 type Category struct {
 	gorm.Model
 	Name        string `gorm:"size:100;not null"`
-	Slug        string `gorm:"size:100;uniqueIndex;not null"`
+	Slug        string `gorm:"size:100;not null;uniqueIndex:idx_categories_slug_alive,where:deleted_at IS NULL"`
 	Description string `gorm:"type:text"`
 
 	ImageURL string `gorm:"size:500"`
 
-	ParentID *uint
-	Parent   *Category `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ParentID  *uint
+	SortOrder int       `gorm:"not null;default:0;index"` // порядок среди соседей
+	Parent    *Category `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 
 	Children []Category `gorm:"foreignKey:ParentID"`
 
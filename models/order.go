@@ -0,0 +1,95 @@
+package models
+
+import "gorm.io/gorm"
+
+type OrderStatus string
+
+const (
+	OrderStatusNew       OrderStatus = "new"
+	OrderStatusConfirmed OrderStatus = "confirmed"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusShipped   OrderStatus = "shipped"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusReturned  OrderStatus = "returned"
+)
+
+// orderTransitions — единственные разрешённые переходы жизненного цикла заказа.
+// cancelled и returned — конечные статусы.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew:       {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:   {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered: {OrderStatusReturned},
+	OrderStatusCancelled: {},
+	OrderStatusReturned:  {},
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// NextStatuses — куда можно перевести заказ из текущего статуса.
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return orderTransitions[s]
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, st := range orderTransitions[s] {
+		if st == next {
+			return true
+		}
+	}
+	return false
+}
+
+// RestocksOnEnter — при переходе в этот статус товар возвращается на склад.
+func (s OrderStatus) RestocksOnEnter() bool {
+	return s == OrderStatusCancelled || s == OrderStatusReturned
+}
+
+type Order struct {
+	gorm.Model
+	UserID *uint       `gorm:"index"`
+	Status OrderStatus `gorm:"size:20;not null;default:'new';index"`
+
+	CustomerName string `gorm:"size:100;not null"`
+	Phone        string `gorm:"size:30;not null"`
+	Address      string `gorm:"size:500"`
+	Comment      string `gorm:"type:text"`
+
+	Subtotal  int64  `gorm:"not null"`
+	PromoCode string `gorm:"size:50"`
+	Discount  int64  `gorm:"not null;default:0"`
+	Total     int64  `gorm:"not null"`
+
+	Items   []OrderItem          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	History []OrderStatusHistory `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// OrderItem хранит снимок товара на момент заказа: название, slug и цена
+// не меняются, даже если товар потом отредактируют или удалят.
+type OrderItem struct {
+	gorm.Model
+	OrderID     uint   `gorm:"index;not null"`
+	ProductID   uint   `gorm:"index;not null"`
+	ProductName string `gorm:"size:100;not null"`
+	ProductSlug string `gorm:"size:100;not null"`
+	VariantID   *uint  `gorm:"index"`
+	Taste       string `gorm:"size:100;not null;default:''"`
+	Price       int64  `gorm:"not null"`
+	Quantity    int    `gorm:"not null"`
+	LineTotal   int64  `gorm:"not null"`
+}
+
+// OrderStatusHistory — журнал смены статусов, одна запись на каждый переход.
+type OrderStatusHistory struct {
+	gorm.Model
+	OrderID    uint        `gorm:"index;not null"`
+	FromStatus OrderStatus `gorm:"size:20;not null;default:''"`
+	ToStatus   OrderStatus `gorm:"size:20;not null"`
+	ChangedBy  *uint       `gorm:"index"`
+	Comment    string      `gorm:"size:500"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestOrderStatusCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{OrderStatusNew, OrderStatusConfirmed, true},
+		{OrderStatusNew, OrderStatusCancelled, true},
+		{OrderStatusNew, OrderStatusPaid, false},
+		{OrderStatusNew, OrderStatusNew, false},
+		{OrderStatusConfirmed, OrderStatusPaid, true},
+		{OrderStatusConfirmed, OrderStatusCancelled, true},
+		{OrderStatusConfirmed, OrderStatusShipped, false},
+		{OrderStatusPaid, OrderStatusShipped, true},
+		{OrderStatusPaid, OrderStatusCancelled, true},
+		{OrderStatusPaid, OrderStatusReturned, false},
+		{OrderStatusShipped, OrderStatusDelivered, true},
+		{OrderStatusShipped, OrderStatusReturned, true},
+		{OrderStatusShipped, OrderStatusCancelled, false},
+		{OrderStatusDelivered, OrderStatusReturned, true},
+		{OrderStatusDelivered, OrderStatusCancelled, false},
+		// конечные статусы
+		{OrderStatusCancelled, OrderStatusNew, false},
+		{OrderStatusCancelled, OrderStatusConfirmed, false},
+		{OrderStatusReturned, OrderStatusDelivered, false},
+		// неизвестные статусы
+		{"unknown", OrderStatusNew, false},
+		{OrderStatusNew, "unknown", false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestOrderStatusValid(t *testing.T) {
+	for _, s := range []OrderStatus{
+		OrderStatusNew, OrderStatusConfirmed, OrderStatusPaid, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned,
+	} {
+		if !s.Valid() {
+			t.Errorf("%s: expected valid", s)
+		}
+	}
+	for _, s := range []OrderStatus{"", "unknown", "NEW"} {
+		if s.Valid() {
+			t.Errorf("%q: expected invalid", s)
+		}
+	}
+}
+
+func TestOrderStatusTerminal(t *testing.T) {
+	for _, s := range []OrderStatus{OrderStatusCancelled, OrderStatusReturned} {
+		if len(s.NextStatuses()) != 0 {
+			t.Errorf("%s: expected no next statuses, got %v", s, s.NextStatuses())
+		}
+		if !s.RestocksOnEnter() {
+			t.Errorf("%s: expected restock on enter", s)
+		}
+	}
+	for _, s := range []OrderStatus{OrderStatusNew, OrderStatusConfirmed, OrderStatusPaid, OrderStatusShipped, OrderStatusDelivered} {
+		if s.RestocksOnEnter() {
+			t.Errorf("%s: unexpected restock on enter", s)
+		}
+	}
+}
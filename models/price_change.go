@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PriceChange — запись истории цены товара; как и складской журнал, только дополняется.
+// Пишется при правке базовой цены или распродажи: по состоянию до и после
+// восстанавливается эффективная цена в любой момент прошлого.
+type PriceChange struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	ProductID uint      `gorm:"index;not null"`
+	OldPrice  int64     `gorm:"not null"`
+	NewPrice  int64     `gorm:"not null"`
+	ChangedBy *uint     `gorm:"index"`
+
+	OldSalePrice    *int64
+	OldSaleStartsAt *time.Time
+	OldSaleEndsAt   *time.Time
+	NewSalePrice    *int64
+	NewSaleStartsAt *time.Time
+	NewSaleEndsAt   *time.Time
+}
@@ -1,24 +1,53 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	gorm.Model
-	Name        string         `gorm:"size:100;not null"`
-	Slug        string         `gorm:"size:100;uniqueIndex;not null"`
-	Description string         `gorm:"type:text"`
-	Price       int64          `gorm:"not null;index"`
-	Stock       int            `gorm:"not null;default:0"`
-	IsActive    bool           `gorm:"not null;default:true"`
-	CategoryID  uint           `gorm:"index"`
-	Category    Category       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
-	Images      []ProductImage `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name        string `gorm:"size:100;not null"`
+	Slug        string `gorm:"size:100;not null;uniqueIndex:idx_products_slug_alive,where:deleted_at IS NULL"`
+	Description string `gorm:"type:text"`
+	Price       int64  `gorm:"not null;index"`
+
+	// CompareAtPrice — «старая» цена для зачёркивания; SalePrice действует
+	// в окне [SaleStartsAt, SaleEndsAt), пустая граница — без ограничения.
+	CompareAtPrice *int64
+	SalePrice      *int64
+	SaleStartsAt   *time.Time `gorm:"index"`
+	SaleEndsAt     *time.Time `gorm:"index"`
+
+	// кэш по одобренным отзывам, пересчитывается при модерации
+	RatingAvg   float64 `gorm:"not null;default:0;index"`
+	RatingCount int     `gorm:"not null;default:0"`
+
+	Stock      int            `gorm:"not null;default:0"`
+	IsActive   bool           `gorm:"not null;default:true"`
+	BrandID    *uint          `gorm:"index"`
+	Brand      *Brand         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	CategoryID uint           `gorm:"index"`
+	Category   Category       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Images     []ProductImage `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 
 	Tastes []ProductTaste `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	Attributes []ProductAttributeValue `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
+// ProductTaste — вариант товара (вкус) со своим остатком, артикулом и,
+// при необходимости, своей ценой. Если у товара есть варианты, Product.Stock
+// равен сумме остатков активных вариантов.
 type ProductTaste struct {
 	gorm.Model
 	ProductID uint   `gorm:"index;not null"`
 	Name      string `gorm:"size:100;not null"`
+	SKU       string `gorm:"size:64;uniqueIndex:idx_product_tastes_sku,where:sku <> '' AND deleted_at IS NULL"`
+	Barcode   string `gorm:"size:64;index"`
+	Stock     int    `gorm:"not null;default:0"`
+	Price     *int64 // nil — цена товара
+	IsActive  bool   `gorm:"not null"`
+	SortOrder int    `gorm:"not null;default:0"`
 }
@@ -1,11 +1,41 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ProductImage struct {
 	gorm.Model
-	ProductID uint   `gorm:"index;not null"`
+	ProductID uint   `gorm:"index;not null;uniqueIndex:idx_product_images_primary,where:is_primary AND deleted_at IS NULL"`
 	URL       string `gorm:"size:500;not null"`
 	IsPrimary bool   `gorm:"not null;default:false"`
 	SortOrder int    `gorm:"not null;default:0"`
+
+	// Variants связаны по URL исходника, а не по ID: один файл может
+	// использоваться несколькими товарами, а нарезается один раз
+	Variants []ImageVariant `gorm:"foreignKey:SourceURL;references:URL;constraint:-"`
+}
+
+const (
+	ImageSizeThumb = "thumb"
+	ImageSizeCard  = "card"
+	ImageSizeFull  = "full"
+
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+)
+
+// ImageVariant — уменьшенная копия загруженного изображения; создаётся фоновым воркером.
+type ImageVariant struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	SourceURL string `gorm:"size:500;not null;uniqueIndex:idx_image_variants_source"`
+	Size      string `gorm:"size:16;not null;uniqueIndex:idx_image_variants_source"`
+	Format    string `gorm:"size:8;not null;uniqueIndex:idx_image_variants_source"`
+	URL       string `gorm:"size:500;not null"`
+	Width     int    `gorm:"not null"`
+	Height    int    `gorm:"not null"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "percent"
+	PromoFixed   PromoKind = "fixed"
+)
+
+// PromoCode — промокод. Если заданы категории или товары, скидка считается
+// только с подходящих позиций; иначе — со всего заказа.
+type PromoCode struct {
+	gorm.Model
+	Code        string    `gorm:"size:50;not null;uniqueIndex:idx_promo_codes_code_alive,where:deleted_at IS NULL"` // хранится в верхнем регистре
+	Description string    `gorm:"size:500"`
+	Kind        PromoKind `gorm:"size:10;not null"`
+	Value       int64     `gorm:"not null"` // проценты или тенге
+	MaxDiscount *int64    // потолок скидки для процентных кодов
+
+	MinOrderTotal int64 `gorm:"not null;default:0"`
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+
+	UsageLimit   *int // всего использований
+	PerUserLimit *int // использований одним пользователем
+	UsedCount    int  `gorm:"not null;default:0"`
+	IsActive     bool `gorm:"not null"` // без default: GORM подставил бы его вместо false
+
+	Categories []Category `gorm:"many2many:promo_code_categories;"`
+	Products   []Product  `gorm:"many2many:promo_code_products;"`
+}
+
+type PromoRedemption struct {
+	gorm.Model
+	PromoCodeID uint  `gorm:"index;not null"`
+	OrderID     uint  `gorm:"uniqueIndex;not null"`
+	UserID      *uint `gorm:"index"`
+	Discount    int64 `gorm:"not null"`
+}
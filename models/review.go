@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// Review — отзыв покупателя; один пользователь — один отзыв на товар.
+// В рейтинг товара попадают только одобренные отзывы.
+type Review struct {
+	gorm.Model
+	ProductID uint         `gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	Product   Product      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uint         `gorm:"not null;uniqueIndex:idx_reviews_product_user;index"`
+	User      User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Rating    int          `gorm:"not null"`
+	Text      string       `gorm:"type:text"`
+	Taste     string       `gorm:"size:100"`
+	Status    ReviewStatus `gorm:"size:20;not null;default:'pending';index"`
+
+	ModeratedBy    *uint
+	ModeratedAt    *time.Time
+	ModerationNote string `gorm:"size:500"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockBatch — партия (лот) товара со сроком годности. Quantity — сколько
+// из партии ещё на складе; меняется только через складской журнал.
+type StockBatch struct {
+	gorm.Model
+	ProductID       uint       `gorm:"index;not null"`
+	VariantID       *uint      `gorm:"index"`
+	LotNumber       string     `gorm:"size:64;not null"`
+	InitialQuantity int        `gorm:"not null"`
+	Quantity        int        `gorm:"not null;default:0"`
+	ExpiresAt       *time.Time `gorm:"index"`
+	ReceivedAt      time.Time  `gorm:"not null"`
+}
+
+// StockBatchAllocation — из какой партии списано (или в какую вернулось)
+// количество по конкретному движению журнала.
+type StockBatchAllocation struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	MovementID uint `gorm:"index;not null"`
+	BatchID    uint `gorm:"index;not null"`
+	Quantity   int  `gorm:"not null"`
+}
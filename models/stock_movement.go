@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type StockMovementKind string
+
+const (
+	StockReceipt    StockMovementKind = "receipt"
+	StockSale       StockMovementKind = "sale"
+	StockReturn     StockMovementKind = "return"
+	StockAdjustment StockMovementKind = "adjustment"
+	StockWriteOff   StockMovementKind = "write_off"
+)
+
+func (k StockMovementKind) Valid() bool {
+	switch k {
+	case StockReceipt, StockSale, StockReturn, StockAdjustment, StockWriteOff:
+		return true
+	}
+	return false
+}
+
+// StockMovement — запись складского журнала. Журнал только дополняется:
+// у записи нет UpdatedAt/DeletedAt, а остаток товара (или варианта) всегда
+// равен сумме Delta по его записям.
+type StockMovement struct {
+	ID        uint              `gorm:"primarykey"`
+	CreatedAt time.Time         `gorm:"index"`
+	ProductID uint              `gorm:"index;not null"`
+	VariantID *uint             `gorm:"index"`
+	Kind      StockMovementKind `gorm:"size:20;not null;index"`
+	Delta     int               `gorm:"not null"`
+	Balance   int               `gorm:"not null"` // остаток после движения
+	Reason    string            `gorm:"size:500"`
+	ActorID   *uint             `gorm:"index"`
+	Reference string            `gorm:"size:100;index"` // например, order:42
+}
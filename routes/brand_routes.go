@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterBrandRoutes(r *gin.Engine) {
+	r.GET("/brands", controllers.ListBrands)
+	r.GET("/brands/:slug", controllers.GetBrand)
+
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
+
+	admin.GET("/brands", controllers.AdminListBrands)
+	admin.GET("/brands/:id", controllers.AdminGetBrand)
+	admin.POST("/brands", controllers.AdminCreateBrand)
+	admin.PUT("/brands/:id", controllers.AdminUpdateBrand)
+	admin.DELETE("/brands/:id", controllers.AdminDeleteBrand)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCartRoutes(r *gin.Engine) {
+	cart := r.Group("/cart")
+	cart.Use(middleware.OptionalAuth())
+
+	cart.GET("", controllers.GetCart)
+	cart.DELETE("", controllers.ClearCart)
+	cart.POST("/items", controllers.AddCartItem)
+	cart.PUT("/items/:id", controllers.UpdateCartItem)
+	cart.DELETE("/items/:id", controllers.RemoveCartItem)
+	cart.POST("/promo", controllers.ApplyPromoToCart)
+}
@@ -9,7 +9,9 @@ import (
 
 func RegisterCategoryRoutes(r *gin.Engine) {
 	r.GET("/categories", controllers.ListCategories)
+	r.GET("/categories/tree", controllers.GetCategoryTree)
 	r.GET("/categories/:slug", controllers.GetCategory)
+	r.GET("/categories/:slug/attributes", controllers.ListCategoryAttributes)
 
 	admin := r.Group("/admin")
 	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
@@ -17,4 +19,10 @@ func RegisterCategoryRoutes(r *gin.Engine) {
 	admin.POST("/categories", controllers.CreateCategory)
 	admin.PUT("/categories/:id", controllers.UpdateCategory)
 	admin.DELETE("/categories/:id", controllers.DeleteCategory)
+	admin.POST("/categories/:id/move", controllers.AdminMoveCategory)
+
+	admin.GET("/categories/:id/attributes", controllers.AdminListAttributes)
+	admin.POST("/categories/:id/attributes", controllers.AdminCreateAttribute)
+	admin.PUT("/attributes/:id", controllers.AdminUpdateAttribute)
+	admin.DELETE("/attributes/:id", controllers.AdminDeleteAttribute)
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOrderRoutes(r *gin.Engine) {
+	r.POST("/orders", middleware.OptionalAuth(), controllers.Checkout)
+
+	my := r.Group("/orders")
+	my.Use(middleware.RequireAuth())
+
+	my.GET("", controllers.ListMyOrders)
+	my.GET("/:id", controllers.GetMyOrder)
+
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
+
+	admin.GET("/orders", controllers.AdminListOrders)
+	admin.GET("/orders/:id", controllers.AdminGetOrder)
+	admin.POST("/orders/:id/status", controllers.AdminUpdateOrderStatus)
+}
@@ -11,6 +11,8 @@ func RegisterProductRoutes(r *gin.Engine) {
 
 	r.GET("/products", controllers.ListProducts)
 	r.GET("/products/:slug", controllers.GetProduct)
+	r.GET("/products/:slug/reviews", controllers.ListProductReviews)
+	r.POST("/products/:slug/reviews", middleware.RequireAuth(), controllers.CreateReview)
 
 	admin := r.Group("/admin")
 	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
@@ -21,4 +23,25 @@ func RegisterProductRoutes(r *gin.Engine) {
 	admin.PUT("/products/:id", controllers.UpdateProduct)
 	admin.DELETE("/products/:id", controllers.DeleteProduct)
 
+	admin.GET("/products/:id/images", controllers.AdminListProductImages)
+	admin.POST("/products/:id/images", controllers.AdminAddProductImage)
+	admin.PUT("/products/:id/images/order", controllers.AdminReorderProductImages)
+	admin.POST("/products/:id/images/:imageId/primary", controllers.AdminSetPrimaryProductImage)
+	admin.DELETE("/products/:id/images/:imageId", controllers.AdminDeleteProductImage)
+
+	admin.GET("/products/:id/price-history", controllers.AdminPriceHistory)
+
+	admin.GET("/products/:id/stock-movements", controllers.AdminListStockMovements)
+	admin.POST("/products/:id/stock-movements", controllers.AdminCreateStockMovement)
+	admin.POST("/products/:id/stock/reconcile", controllers.AdminReconcileStock)
+
+	admin.GET("/products/:id/batches", controllers.AdminListBatches)
+	admin.POST("/products/:id/batches", controllers.AdminReceiveBatch)
+	admin.POST("/batches/:id/write-off", controllers.AdminWriteOffBatch)
+	admin.GET("/batches/expiring", controllers.AdminExpiringBatches)
+
+	admin.GET("/reviews", controllers.AdminListReviews)
+	admin.POST("/reviews/:id/moderate", controllers.AdminModerateReview)
+	admin.DELETE("/reviews/:id", controllers.AdminDeleteReview)
+
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPromoRoutes(r *gin.Engine) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
+
+	admin.GET("/promo-codes", controllers.AdminListPromoCodes)
+	admin.GET("/promo-codes/:id", controllers.AdminGetPromoCode)
+	admin.POST("/promo-codes", controllers.AdminCreatePromoCode)
+	admin.PUT("/promo-codes/:id", controllers.AdminUpdatePromoCode)
+	admin.DELETE("/promo-codes/:id", controllers.AdminDeletePromoCode)
+}
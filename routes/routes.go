@@ -18,7 +18,7 @@ func SetupRoutes() *gin.Engine {
 			"https://clen.kz",
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Cart-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -34,6 +34,13 @@ func SetupRoutes() *gin.Engine {
 	RegisterProductRoutes(r)
 	RegisterAuthRoutes(r)
 	RegisterUserRoutes(r)
+	RegisterCartRoutes(r)
+	RegisterOrderRoutes(r)
+	RegisterPromoRoutes(r)
+	RegisterBrandRoutes(r)
+	RegisterSearchRoutes(r)
+	RegisterUploadRoutes(r)
+	RegisterTrashRoutes(r)
 
 	return r
 }
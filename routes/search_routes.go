@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSearchRoutes(r *gin.Engine) {
+	r.GET("/search/suggest", controllers.SearchSuggest)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterTrashRoutes(r *gin.Engine) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
+
+	admin.GET("/trash", controllers.AdminListTrash)
+	admin.POST("/trash/:type/:id/restore", controllers.AdminRestoreTrash)
+	admin.DELETE("/trash/:type/:id", controllers.AdminPurgeTrash)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"clen_shop/controllers"
+	"clen_shop/middleware"
+	"clen_shop/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterUploadRoutes(r *gin.Engine) {
+	// локальное хранилище раздаём сами; для S3 файлы отдаёт само хранилище
+	if l, ok := storage.Default.(*storage.Local); ok {
+		r.Static(l.URLPath(), l.Dir)
+	}
+
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAuth(), middleware.RequireRole("admin"))
+
+	admin.POST("/uploads", controllers.AdminUpload)
+	admin.POST("/uploads/cleanup", controllers.AdminUploadsCleanup)
+}
@@ -0,0 +1,239 @@
+// Package search — полнотекстовый поиск товаров на Postgres.
+//
+// У товара есть колонка search_vector (tsvector), собранная из названия,
+// бренда, категории, вкусов и описания в русской и английской конфигурациях.
+// Колонку обновляют обработчики, меняющие эти данные (RefreshProducts,
+// RefreshCategory, RefreshBrand), поиск идёт по GIN-индексу.
+//
+// Если точный поиск ничего не нашёл, включается нечёткий: запрос в другой
+// раскладке и транслитерации плюс триграммное сходство (pg_trgm) с колонкой
+// search_text — «свёрнутым» (см. Fold) текстом названия, бренда, категории и вкусов.
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// vectorSQL собирает tsvector для строки products (алиас p).
+// Веса: A — название и бренд, B — категория и вкусы, C/D — описание.
+const vectorSQL = `
+	setweight(to_tsvector('russian', coalesce(p.name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(p.name, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce((
+		SELECT b.name FROM brands b WHERE b.id = p.brand_id
+	), '')), 'A') ||
+	setweight(to_tsvector('russian', coalesce((
+		SELECT c.name FROM categories c WHERE c.id = p.category_id
+	), '')), 'B') ||
+	setweight(to_tsvector('russian', coalesce((
+		SELECT string_agg(t.name, ' ') FROM product_tastes t
+		WHERE t.product_id = p.id AND t.deleted_at IS NULL
+	), '')), 'B') ||
+	setweight(to_tsvector('russian', coalesce(p.description, '')), 'C') ||
+	setweight(to_tsvector('english', coalesce(p.description, '')), 'D')`
+
+// querySQL — запрос покупателя в обеих конфигурациях; «протеина» и «protein»
+// сводятся к своим основам и совпадают с «протеин»/«proteins».
+const querySQL = `(websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))`
+
+// Setup создаёт колонки и индексы и заполняет их для строк, где они ещё пусты.
+func Setup(db *gorm.DB) error {
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_text text`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_text ON products USING GIN (search_text gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_product_tastes_name_trgm ON product_tastes USING GIN (name gin_trgm_ops)`,
+		`UPDATE products p SET search_vector = ` + vectorSQL + ` WHERE p.search_vector IS NULL`,
+	}
+	for _, s := range stmts {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return refreshText(db, "p.search_text IS NULL")
+}
+
+// RefreshProducts пересобирает поисковые данные указанных товаров.
+func RefreshProducts(db *gorm.DB, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := db.Exec(`UPDATE products p SET search_vector = `+vectorSQL+` WHERE p.id IN ?`, ids).Error; err != nil {
+		return err
+	}
+	return refreshText(db, "p.id IN ?", ids)
+}
+
+// RefreshCategory пересобирает поисковые данные товаров категории (например, после переименования).
+func RefreshCategory(db *gorm.DB, categoryID uint) error {
+	if err := db.Exec(`UPDATE products p SET search_vector = `+vectorSQL+` WHERE p.category_id = ?`, categoryID).Error; err != nil {
+		return err
+	}
+	return refreshText(db, "p.category_id = ?", categoryID)
+}
+
+// RefreshBrand пересобирает поисковые данные товаров бренда.
+func RefreshBrand(db *gorm.DB, brandID uint) error {
+	if err := db.Exec(`UPDATE products p SET search_vector = `+vectorSQL+` WHERE p.brand_id = ?`, brandID).Error; err != nil {
+		return err
+	}
+	return refreshText(db, "p.brand_id = ?", brandID)
+}
+
+// refreshText заполняет search_text. Транслитерация делается в Go,
+// поэтому колонку нельзя собрать одним UPDATE, как search_vector.
+func refreshText(db *gorm.DB, where string, args ...interface{}) error {
+	type row struct {
+		ID       uint
+		Name     string
+		Brand    string
+		Category string
+		Tastes   string
+	}
+	var rows []row
+	if err := db.Raw(`
+		SELECT p.id, p.name,
+			coalesce(b.name, '') AS brand,
+			coalesce(c.name, '') AS category,
+			coalesce((SELECT string_agg(t.name, ' ') FROM product_tastes t
+				WHERE t.product_id = p.id AND t.deleted_at IS NULL), '') AS tastes
+		FROM products p
+		LEFT JOIN brands b ON b.id = p.brand_id
+		LEFT JOIN categories c ON c.id = p.category_id
+		WHERE `+where, args...).Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, r := range rows {
+		text := Fold(r.Name + " " + r.Brand + " " + r.Category + " " + r.Tastes)
+		if err := db.Exec(`UPDATE products SET search_text = ? WHERE id = ?`, text, r.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match оставляет товары, подходящие под запрос q.
+func Match(db *gorm.DB, q string) *gorm.DB {
+	return db.Where("products.search_vector @@ "+querySQL, q, q)
+}
+
+// Prefix — поиск по началу слов для подсказок: «прот шок» находит
+// «Протеин … шоколад». Результат уже отсортирован по релевантности.
+func Prefix(db *gorm.DB, q string) *gorm.DB {
+	pq := prefixQuery(q)
+	if pq == "" {
+		return db.Where("false")
+	}
+	const tsq = `(to_tsquery('russian', ?) || to_tsquery('english', ?))`
+	return db.Where("products.search_vector @@ "+tsq, pq, pq).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank_cd(products.search_vector, " + tsq + ") DESC, products.id DESC",
+			Vars:               []interface{}{pq, pq},
+			WithoutParentheses: true,
+		}})
+}
+
+// prefixQuery превращает «протеин шок» в «протеин:* & шок:*» для to_tsquery;
+// всё, кроме букв и цифр, отбрасывается, чтобы запрос не ломал синтаксис tsquery.
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Fuzzy — нечёткий поиск: полнотекстовый по другим написаниям запроса
+// (раскладка, транслитерация) или триграммное сходство слов с search_text.
+func Fuzzy(db *gorm.DB, q string) *gorm.DB {
+	conds := make([]string, 0)
+	vars := make([]interface{}, 0)
+	for _, alt := range spellings(q) {
+		conds = append(conds, "products.search_vector @@ "+querySQL)
+		vars = append(vars, alt, alt)
+	}
+	for _, f := range folded(q) {
+		conds = append(conds, "? <% products.search_text")
+		vars = append(vars, f)
+	}
+	if len(conds) == 0 {
+		return db.Where("false")
+	}
+	return db.Where("("+strings.Join(conds, " OR ")+")", vars...)
+}
+
+// Apply применяет поиск: точный, а если он ничего не дал — нечёткий.
+// Второе значение сообщает, что результат нечёткий.
+func Apply(db *gorm.DB, q string) (*gorm.DB, bool, error) {
+	base := db.Session(&gorm.Session{})
+
+	exact := Match(base, q)
+	var ids []uint
+	if err := exact.Session(&gorm.Session{}).Limit(1).Pluck("products.id", &ids).Error; err != nil {
+		return nil, false, err
+	}
+	if len(ids) > 0 {
+		return exact, false, nil
+	}
+	return Fuzzy(base, q), true, nil
+}
+
+// OrderByRank сортирует по релевантности (сначала лучшие совпадения):
+// для точного поиска — по ts_rank, для нечёткого — по сходству слов.
+func OrderByRank(db *gorm.DB, q string, fuzzy bool) *gorm.DB {
+	expr := clause.Expr{
+		SQL:                "ts_rank_cd(products.search_vector, " + querySQL + ") DESC, products.id DESC",
+		Vars:               []interface{}{q, q},
+		WithoutParentheses: true,
+	}
+	if fuzzy {
+		parts := make([]string, 0)
+		vars := make([]interface{}, 0)
+		for _, f := range folded(q) {
+			parts = append(parts, "word_similarity(?, products.search_text)")
+			vars = append(vars, f)
+		}
+		if len(parts) > 0 {
+			expr = clause.Expr{
+				SQL:                "GREATEST(" + strings.Join(parts, ", ") + ") DESC, products.id DESC",
+				Vars:               vars,
+				WithoutParentheses: true,
+			}
+		}
+	}
+	return db.Order(clause.OrderBy{Expression: expr})
+}
+
+// spellings — другие написания запроса: в соседней раскладке и в транслитерации.
+func spellings(q string) []string {
+	return unique(q, SwapLayout(q), ToCyrillic(q), ToLatin(q))
+}
+
+// folded — запрос и его вариант в соседней раскладке в виде для триграмм.
+func folded(q string) []string {
+	return unique("", Fold(q), Fold(SwapLayout(q)))
+}
+
+// unique возвращает непустые различные значения, кроме skip.
+func unique(skip string, values ...string) []string {
+	seen := map[string]bool{skip: true, "": true}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
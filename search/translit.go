@@ -0,0 +1,131 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latinDigraphs проверяются раньше одиночных букв; порядок важен (sch раньше sh).
+var latinDigraphs = []struct{ from, to string }{
+	{"sch", "щ"}, {"shch", "щ"},
+	{"sh", "ш"}, {"ch", "ч"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"wh", "в"}, {"ph", "ф"}, {"th", "т"}, {"ck", "к"},
+	{"ee", "и"}, {"oo", "у"},
+}
+
+var latinToCyr = map[rune]string{
+	'a': "а", 'b': "б", 'c': "к", 'd': "д", 'e': "е", 'f': "ф", 'g': "г",
+	'h': "х", 'i': "и", 'j': "дж", 'k': "к", 'l': "л", 'm': "м", 'n': "н",
+	'o': "о", 'p': "п", 'q': "к", 'r': "р", 's': "с", 't': "т", 'u': "у",
+	'v': "в", 'w': "в", 'x': "кс", 'y': "й", 'z': "з",
+}
+
+var cyrToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// раскладки клавиатуры: одна и та же клавиша в QWERTY и ЙЦУКЕН
+const (
+	qwertyKeys = "qwertyuiop[]asdfghjkl;'zxcvbnm,.`"
+	jcukenKeys = "йцукенгшщзхъфывапролджэячсмитьбюё"
+)
+
+var layoutSwap = func() map[rune]rune {
+	m := make(map[rune]rune)
+	lat, cyr := []rune(qwertyKeys), []rune(jcukenKeys)
+	for i := range lat {
+		m[lat[i]] = cyr[i]
+		m[cyr[i]] = lat[i]
+	}
+	return m
+}()
+
+// ToCyrillic транслитерирует латиницу в кириллицу: «protein» → «протеин».
+func ToCyrillic(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		matched := false
+		for _, d := range latinDigraphs {
+			if strings.HasPrefix(s[i:], d.from) {
+				b.WriteString(d.to)
+				i += len(d.from)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r, size := rune(s[i]), 1
+		if r >= 0x80 {
+			r = []rune(s[i:])[0]
+			size = len(string(r))
+		}
+		if cyr, ok := latinToCyr[r]; ok {
+			b.WriteString(cyr)
+		} else {
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// ToLatin транслитерирует кириллицу в латиницу: «протеин» → «protein».
+func ToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if lat, ok := cyrToLatin[r]; ok {
+			b.WriteString(lat)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SwapLayout исправляет текст, набранный не в той раскладке: «ghjntby» → «протеин».
+func SwapLayout(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if sw, ok := layoutSwap[r]; ok {
+			b.WriteRune(sw)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Fold приводит текст к единому виду для триграммного сравнения: кириллица,
+// нижний регистр, без различий «е/ё/э» и мягкого/твёрдого знака. Так «whey»
+// и «вэй» дают одно и то же «вей».
+func Fold(s string) string {
+	s = ToCyrillic(s)
+	var b strings.Builder
+	space := true
+	for _, r := range s {
+		switch r {
+		case 'ё', 'э':
+			r = 'е'
+		case 'ъ', 'ь':
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if !space {
+				b.WriteByte(' ')
+				space = true
+			}
+			continue
+		}
+		b.WriteRune(r)
+		space = false
+	}
+	return strings.TrimSpace(b.String())
+}
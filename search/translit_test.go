@@ -0,0 +1,82 @@
+package search
+
+import "testing"
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"protein", "протеин"},
+		{"Whey", "вей"},
+		{"Shchuka", "щука"},
+		{"chocolate", "чоколате"},
+		{"x", "кс"},
+		{"jam", "джам"},
+		{"BCAA", "бкаа"},
+		// кириллица, цифры и знаки остаются как есть
+		{"кофе", "кофе"},
+		{"Whey Protein 80%", "вей протеин 80%"},
+		{"café", "кафé"},
+	}
+	for _, tt := range tests {
+		if got := ToCyrillic(tt.in); got != tt.want {
+			t.Errorf("ToCyrillic(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToLatin(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Протеин", "protein"},
+		{"щука", "schuka"},
+		{"ёж", "ezh"},
+		{"Сывороточный", "syvorotochnyy"},
+		{"объём", "obem"},
+		{"Hello, World", "hello, world"},
+	}
+	for _, tt := range tests {
+		if got := ToLatin(tt.in); got != tt.want {
+			t.Errorf("ToLatin(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSwapLayout(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"ghjntby", "протеин"},
+		{"Протеин", "ghjntby"},
+		{"руддщ", "hello"},
+		{"rjat", "кофе"},
+		{"ntcn 80%", "тест 80%"},
+	}
+	for _, tt := range tests {
+		if got := SwapLayout(tt.in); got != tt.want {
+			t.Errorf("SwapLayout(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"whey", "вей"},
+		{"вэй", "вей"},
+		{"Объём!!  123", "обем 123"},
+		{"Hello, World", "хелло ворлд"},
+		{"  Whey Protein 80%  ", "вей протеин 80"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local хранит файлы в каталоге Dir и отдаёт их по BaseURL
+// (сам каталог раздаётся роутером, см. Local.URLPath).
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: create %s: %w", dir, err)
+	}
+	return &Local{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// URLPath — путь из BaseURL, по которому роутер раздаёт каталог.
+func (l *Local) URLPath() string {
+	u, err := url.Parse(l.BaseURL)
+	if err != nil || u.Path == "" {
+		return "/uploads"
+	}
+	return u.Path
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	// пишем во временный файл и переименовываем, чтобы не отдавать недописанное
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) List(ctx context.Context) ([]Object, error) {
+	var objs []Object
+	err := filepath.WalkDir(l.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// временные файлы недописанных загрузок пропускаем
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.Dir, p)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, Object{
+			Key:     filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	return objs, err
+}
+
+func (l *Local) URL(key string) string {
+	return l.BaseURL + "/" + key
+}
+
+// Key сверяет только путь: ссылки, сохранённые до смены домена или
+// UPLOAD_BASE_URL с тем же путём, по-прежнему указывают на свои файлы.
+func (l *Local) Key(u string) (string, bool) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+	key, ok := strings.CutPrefix(parsed.Path, l.URLPath()+"/")
+	if !ok || !validKey(key) {
+		return "", false
+	}
+	return key, true
+}
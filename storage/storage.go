@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Storage — хранилище загруженных файлов. Ключ — относительный путь вида
+// "2026/10/abcdef.jpg"; публичный URL строится реализацией. Сейчас есть только
+// локальная файловая система, S3-совместимое хранилище (MinIO и т.п.)
+// подключается отдельной реализацией этого интерфейса.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]Object, error)
+
+	// URL — публичный адрес файла по ключу
+	URL(key string) string
+	// Key — обратное преобразование по пути URL (хост не учитывается);
+	// false, если URL не из этого хранилища
+	Key(url string) (string, bool)
+}
+
+// Object — файл в хранилище.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// Default — хранилище приложения, настраивается в Setup.
+var Default Storage
+
+// Setup выбирает реализацию по STORAGE_DRIVER (пока только local).
+func Setup() error {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "local"
+	}
+
+	switch driver {
+	case "local":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		baseURL := os.Getenv("UPLOAD_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080/uploads"
+		}
+		l, err := NewLocal(dir, baseURL)
+		if err != nil {
+			return err
+		}
+		Default = l
+		return nil
+	default:
+		return fmt.Errorf("storage: unknown driver %q", driver)
+	}
+}
+
+// validKey не пускает ключи за пределы хранилища.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
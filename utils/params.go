@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParamID разбирает числовой параметр пути; false — если это не положительное число.
+func ParamID(c *gin.Context, name string) (uint, bool) {
+	n, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	return uint(n), true
+}